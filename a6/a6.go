@@ -46,6 +46,7 @@ func (s *state) AuthEnc(plaintext, data []byte) []byte {
 		panic("RNG failed")
 	}
 	sp := sha3.NewShake256()
+	defer sp.(sha3.Wiper).Wipe()
 	sp.Write(s.hashKey[:])
 	sp.Write(nonce)
 	sp.Write(data)
@@ -67,6 +68,7 @@ func (s *state) AuthDec(ciphertext, data []byte) ([]byte, error) {
 	purportedTag := ciphertext[:tagLen]
 	ciphertext = ciphertext[tagLen:]
 	sp := sha3.NewShake256()
+	defer sp.(sha3.Wiper).Wipe()
 	sp.Write(s.hashKey[:])
 	sp.Write(nonce)
 	sp.Write(data)
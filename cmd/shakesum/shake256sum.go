@@ -21,13 +21,14 @@ func init() {
 	flag.StringVar(&macKey, "mackey", "", "an ASCII MAC key")
 }
 
-func sumFile(filename string) (checksum string, err error) {
+func sumFile(filename string, key []byte) (checksum string, err error) {
 	f, err := os.Open(filename)
 	if err != nil {
 		return "", err
 	}
 	sp := sha3.NewShake256()
-	sp.Write([]byte(macKey))
+	defer sp.(sha3.Wiper).Wipe()
+	sp.Write(key)
 	_, err = io.Copy(sp, f)
 	if err != nil {
 		return "", err
@@ -38,9 +39,10 @@ func sumFile(filename string) (checksum string, err error) {
 	return checksum, nil
 }
 
-func sumReader(r io.Reader) (checksum string) {
+func sumReader(r io.Reader, key []byte) (checksum string) {
 	sp := sha3.NewShake256()
-	sp.Write([]byte(macKey))
+	defer sp.(sha3.Wiper).Wipe()
+	sp.Write(key)
 	_, err := io.Copy(sp, r)
 	if err != nil {
 		fmt.Errorf("error reading %v: %s\n", r, err)
@@ -53,13 +55,22 @@ func sumReader(r io.Reader) (checksum string) {
 
 func main() {
 	flag.Parse()
+	// Convert the key once, and clear the copy when done. This is best
+	// effort: the flag string and os.Args still hold the key, and Go
+	// strings can not be cleared.
+	key := []byte(macKey)
+	defer func() {
+		for i := range key {
+			key[i] = 0
+		}
+	}()
 	if flag.NArg() == 0 {
 		// Read from stdin
-		checksum := sumReader(os.Stdin)
+		checksum := sumReader(os.Stdin, key)
 		fmt.Println(checksum)
 	} else {
 		for _, filename := range flag.Args() {
-			checksum, err := sumFile(filename)
+			checksum, err := sumFile(filename, key)
 			if err != nil {
 				fmt.Errorf("error %s on %s", err, filename)
 			}
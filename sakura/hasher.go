@@ -0,0 +1,125 @@
+package sakura
+
+import (
+	"hash"
+)
+
+// A Hasher computes the tree hash of a message written to it in any
+// number of pieces. It holds the current leaf and the chaining values of
+// the nodes that are not complete yet: at most Fanout per level, except at
+// the level below the root when the height is limited.
+type Hasher struct {
+	p    Params
+	leaf []byte // the current leaf, hashed once more input follows it
+
+	// levels holds the chaining values not yet grouped into a node, level
+	// by level from the leaves up. A level is grouped only when it has
+	// more than Fanout chaining values, since the root takes up to Fanout
+	// of them directly.
+	levels [][][Size]byte
+}
+
+// New returns a new Hasher for trees with parameters p. It returns an
+// error if the parameters are invalid.
+func New(p Params) (*Hasher, error) {
+	if err := p.check(); err != nil {
+		return nil, err
+	}
+	return &Hasher{p: p, leaf: make([]byte, 0, p.LeafSize)}, nil
+}
+
+var _ hash.Hash = (*Hasher)(nil)
+
+// Size returns the size of the output.
+func (h *Hasher) Size() int { return Size }
+
+// BlockSize returns the leaf size.
+func (h *Hasher) BlockSize() int { return h.p.LeafSize }
+
+// Reset resets the Hasher to hash a new message.
+func (h *Hasher) Reset() {
+	h.leaf = h.leaf[:0]
+	h.levels = nil
+}
+
+// Write adds more data to the message. It never returns an error.
+func (h *Hasher) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		if len(h.leaf) == h.p.LeafSize {
+			// The leaf is followed by more input, so it is not the
+			// single node of the tree.
+			var cv [Size]byte
+			hashMessage(&cv, h.leaf, false)
+			h.levels = h.push(h.levels, 0, cv)
+			h.leaf = h.leaf[:0]
+		}
+		todo := h.p.LeafSize - len(h.leaf)
+		if todo > len(p) {
+			todo = len(p)
+		}
+		h.leaf = append(h.leaf, p[:todo]...)
+		p = p[todo:]
+	}
+	return n, nil
+}
+
+// push appends cv to level k of levels, first grouping the chaining values
+// of that level into an inner node if it is full.
+func (h *Hasher) push(levels [][][Size]byte, k int, cv [Size]byte) [][][Size]byte {
+	if k == len(levels) {
+		levels = append(levels, nil)
+	}
+	rootLevel := h.p.Height != 0 && k+1 == h.p.Height-1
+	if !rootLevel && len(levels[k]) == h.p.Fanout {
+		var up [Size]byte
+		hashCVs(&up, levels[k], false)
+		levels[k] = levels[k][:0]
+		levels = h.push(levels, k+1, up)
+	}
+	levels[k] = append(levels[k], cv)
+	return levels
+}
+
+// Sum appends the hash of the message written so far to b. It does not
+// change the state of the Hasher.
+func (h *Hasher) Sum(b []byte) []byte {
+	var out [Size]byte
+	if len(h.levels) == 0 {
+		hashMessage(&out, h.leaf, true)
+		return append(b, out[:]...)
+	}
+
+	// Complete the tree on a copy of the pending chaining values.
+	levels := make([][][Size]byte, len(h.levels))
+	for k := range h.levels {
+		levels[k] = append([][Size]byte(nil), h.levels[k]...)
+	}
+	if len(h.leaf) > 0 {
+		var cv [Size]byte
+		hashMessage(&cv, h.leaf, false)
+		levels = h.push(levels, 0, cv)
+	}
+	for k := 0; ; k++ {
+		if h.p.isRootLevel(k+1, len(levels[k]), len(levels) > k+1) {
+			hashCVs(&out, levels[k], true)
+			return append(b, out[:]...)
+		}
+		var up [Size]byte
+		hashCVs(&up, levels[k], false)
+		levels = h.push(levels, k+1, up)
+	}
+}
+
+// Sum returns the tree hash of data with parameters p, or an error if the
+// parameters are invalid.
+func Sum(p Params, data []byte) ([Size]byte, error) {
+	var out [Size]byte
+	h, err := New(p)
+	if err != nil {
+		return out, err
+	}
+	h.Write(data)
+	h.Sum(out[:0])
+	return out, nil
+}
@@ -0,0 +1,146 @@
+// Package sakura implements tree hashing on the SHA-3 sponge, with the
+// nodes coded according to Sakura (http://keccak.noekeon.org/Sakura.pdf),
+// so that the hash of a large message can be computed in pieces and
+// updated when part of the message changes.
+//
+// The message is cut into leaves of Params.LeafSize bytes; the last leaf
+// may be shorter. Each leaf is hashed into a 32-byte chaining value.
+// Chaining values are then grouped, Params.Fanout at a time, into inner
+// nodes, which are hashed into chaining values of their own, level after
+// level, until a level has at most Params.Fanout chaining values or the
+// level below the root is reached. The root, or final node, is then
+// hashed from the chaining values of that level into the 32-byte output.
+// A message of at most one leaf is hashed as a single final node.
+//
+// The inner function is the Keccak-f[1600] sponge with a capacity of 256
+// bits, as in SHAKE128, and the nodes are coded as in KangarooTwelve,
+// without kangaroo hopping:
+//
+//	single node:   message || 11
+//	leaf:          leaf || 110
+//	inner node:    CVs || length_encode(n) || FF FF || 010
+//	final node:    CVs || length_encode(n) || FF FF || 01
+//
+// where the suffixes are in bits and n is the number of chaining values.
+// The last bit of each node tells whether it is final; the bit before it,
+// or before the bits 10 in inner nodes, tells whether the node contains
+// message bits or chaining values; FF FF states that the chaining values
+// are not interleaved.
+package sakura
+
+import (
+	"errors"
+
+	"code.google.com/p/go.crypto/sha3"
+)
+
+const (
+	// Size is the size of the chaining values and of the output.
+	Size = 32
+
+	// rate is the rate of the sponge, in bytes.
+	rate = 168
+
+	// Domain separation values for the nodes: their Sakura suffix
+	// followed by the first bit of the padding.
+	dsbyteSingle = 0x07
+	dsbyteLeaf   = 0x0b
+	dsbyteInner  = 0x0a
+	dsbyteFinal  = 0x06
+)
+
+var (
+	errLeafSize = errors.New("sakura: leaf size must be positive")
+	errFanout   = errors.New("sakura: fanout must be at least 2")
+	errHeight   = errors.New("sakura: height must be zero or at least 2")
+)
+
+// Params are the parameters of the tree.
+type Params struct {
+	// LeafSize is the number of message bytes in each leaf.
+	LeafSize int
+
+	// Fanout is the largest number of chaining values in an inner node.
+	Fanout int
+
+	// Height is the largest number of levels of the tree, counting the
+	// leaves and the root. The root takes as many chaining values as the
+	// level below it has. Height 2 gives a root over all the leaves, as in
+	// KangarooTwelve; zero puts no limit on the height.
+	Height int
+}
+
+func (p *Params) check() error {
+	if p.LeafSize <= 0 {
+		return errLeafSize
+	}
+	if p.Fanout < 2 {
+		return errFanout
+	}
+	if p.Height != 0 && p.Height < 2 {
+		return errHeight
+	}
+	return nil
+}
+
+// isRootLevel reports whether the chaining values of level k, counting the
+// leaves as level 1, of which there are n, are hashed by the root.
+// moreAbove reports whether an upper level already holds chaining values.
+func (p *Params) isRootLevel(k, n int, moreAbove bool) bool {
+	if p.Height != 0 && k == p.Height-1 {
+		return true
+	}
+	return !moreAbove && n <= p.Fanout
+}
+
+// newSponge returns a new sponge for a node with the given suffix.
+func newSponge(dsbyte byte) *sha3.Sponge {
+	sp, err := sha3.NewSponge(rate, dsbyte, 24)
+	if err != nil {
+		panic(err)
+	}
+	return sp
+}
+
+// hashMessage hashes a leaf, or the single node if final is set, into out.
+func hashMessage(out *[Size]byte, msg []byte, final bool) {
+	dsbyte := byte(dsbyteLeaf)
+	if final {
+		dsbyte = dsbyteSingle
+	}
+	sp := newSponge(dsbyte)
+	sp.Absorb(msg)
+	sp.Squeeze(out[:])
+}
+
+// hashCVs hashes an inner node, or the final node if final is set, with
+// chaining values cvs into out.
+func hashCVs(out *[Size]byte, cvs [][Size]byte, final bool) {
+	dsbyte := byte(dsbyteInner)
+	if final {
+		dsbyte = dsbyteFinal
+	}
+	sp := newSponge(dsbyte)
+	for i := range cvs {
+		sp.Absorb(cvs[i][:])
+	}
+	sp.Absorb(lengthEncode(uint64(len(cvs))))
+	sp.Absorb([]byte{0xff, 0xff})
+	sp.Squeeze(out[:])
+}
+
+// lengthEncode returns the encoding of x as defined by length_encode in
+// RFC 9861: the big-endian bytes of x, with all leading zeros removed,
+// followed by their count.
+func lengthEncode(x uint64) []byte {
+	var b [9]byte
+	n := 0
+	for y := x; y > 0; y >>= 8 {
+		n++
+	}
+	for i := 0; i < n; i++ {
+		b[i] = byte(x >> uint(8*(n-1-i)))
+	}
+	b[n] = byte(n)
+	return b[:n+1]
+}
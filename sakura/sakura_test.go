@@ -0,0 +1,169 @@
+package sakura
+
+import (
+	"bytes"
+	"testing"
+
+	"code.google.com/p/go.crypto/sha3"
+)
+
+func sequentialBytes(size int) []byte {
+	result := make([]byte, size)
+	for i := range result {
+		result[i] = byte(i)
+	}
+	return result
+}
+
+// node hashes a node given as its bytes and Sakura suffix.
+func node(t *testing.T, data []byte, dsbyte byte) []byte {
+	sp, err := sha3.NewSponge(168, dsbyte, 24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp.Absorb(data)
+	out := make([]byte, Size)
+	sp.Squeeze(out)
+	return out
+}
+
+func cat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+// TestCoding checks small trees against nodes built by hand.
+func TestCoding(t *testing.T) {
+	msg := sequentialBytes(10)
+	ff := []byte{0xff, 0xff}
+
+	// A single node.
+	want := node(t, msg[:4], 0x07)
+	if got, _ := Sum(Params{LeafSize: 4, Fanout: 2}, msg[:4]); !bytes.Equal(got[:], want) {
+		t.Errorf("single node: got %x, want %x", got, want)
+	}
+
+	// Three leaves under the root.
+	l0, l1, l2 := node(t, msg[:4], 0x0b), node(t, msg[4:8], 0x0b), node(t, msg[8:], 0x0b)
+	want = node(t, cat(l0, l1, l2, []byte{3, 1}, ff), 0x06)
+	if got, _ := Sum(Params{LeafSize: 4, Fanout: 3}, msg); !bytes.Equal(got[:], want) {
+		t.Errorf("height 2: got %x, want %x", got, want)
+	}
+	if got, _ := Sum(Params{LeafSize: 4, Fanout: 2, Height: 2}, msg); !bytes.Equal(got[:], want) {
+		t.Errorf("height limited to 2: got %x, want %x", got, want)
+	}
+
+	// Three leaves, grouped two by two.
+	i0 := node(t, cat(l0, l1, []byte{2, 1}, ff), 0x0a)
+	i1 := node(t, cat(l2, []byte{1, 1}, ff), 0x0a)
+	want = node(t, cat(i0, i1, []byte{2, 1}, ff), 0x06)
+	if got, _ := Sum(Params{LeafSize: 4, Fanout: 2}, msg); !bytes.Equal(got[:], want) {
+		t.Errorf("height 3: got %x, want %x", got, want)
+	}
+}
+
+var testParams = []Params{
+	{LeafSize: 16, Fanout: 2},
+	{LeafSize: 16, Fanout: 3},
+	{LeafSize: 7, Fanout: 4, Height: 3},
+	{LeafSize: 5, Fanout: 2, Height: 2},
+	{LeafSize: 100, Fanout: 16},
+}
+
+// TestHasherMatchesTree checks that the streaming Hasher, fed in pieces,
+// and the Tree compute the same hash.
+func TestHasherMatchesTree(t *testing.T) {
+	msg := sequentialBytes(2000)
+	for _, p := range testParams {
+		for _, n := range []int{0, 1, p.LeafSize, p.LeafSize + 1, 2 * p.LeafSize, 9 * p.LeafSize, 9*p.LeafSize + 3, 2000} {
+			tree, err := NewTree(p, bytes.NewReader(msg[:n]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := tree.Sum(nil)
+
+			h, _ := New(p)
+			for i, step := 0, 1; i < n; i, step = i+step, step*2+1 {
+				end := i + step
+				if end > n {
+					end = n
+				}
+				h.Write(msg[i:end])
+			}
+			if got := h.Sum(nil); !bytes.Equal(got, want) {
+				t.Errorf("%+v, %d bytes: Hasher got %x, Tree %x", p, n, got, want)
+			}
+			if got := h.Sum(nil); !bytes.Equal(got, want) {
+				t.Errorf("%+v, %d bytes: second Sum got %x, want %x", p, n, got, want)
+			}
+		}
+	}
+}
+
+// TestUpdateLeaf checks that updating leaves gives the hash of the
+// modified message.
+func TestUpdateLeaf(t *testing.T) {
+	for _, p := range testParams {
+		for _, n := range []int{1, p.LeafSize, 9*p.LeafSize + 3, 1000} {
+			msg := sequentialBytes(n)
+			tree, err := NewTree(p, bytes.NewReader(msg))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, i := range []int{0, tree.NumLeaves() / 2, tree.NumLeaves() - 1} {
+				start := i * p.LeafSize
+				end := start + p.LeafSize
+				if end > n {
+					end = n
+				}
+				leaf := bytes.Repeat([]byte{byte(i + 1)}, end-start)
+				copy(msg[start:], leaf)
+				if err := tree.UpdateLeaf(i, leaf); err != nil {
+					t.Fatalf("%+v, %d bytes: UpdateLeaf(%d): %v", p, n, i, err)
+				}
+				want, _ := Sum(p, msg)
+				if got := tree.Sum(nil); !bytes.Equal(got, want[:]) {
+					t.Errorf("%+v, %d bytes, leaf %d: got %x, want %x", p, n, i, got, want)
+				}
+			}
+		}
+	}
+}
+
+// TestUpdateLeafErrors checks that invalid updates are rejected.
+func TestUpdateLeafErrors(t *testing.T) {
+	p := Params{LeafSize: 4, Fanout: 2}
+	tree, _ := NewTree(p, bytes.NewReader(sequentialBytes(10)))
+	for _, tc := range []struct {
+		i    int
+		leaf []byte
+	}{
+		{-1, make([]byte, 4)},
+		{3, make([]byte, 4)},
+		{0, make([]byte, 3)},
+		{2, make([]byte, 5)},
+		{2, nil},
+	} {
+		if err := tree.UpdateLeaf(tc.i, tc.leaf); err == nil {
+			t.Errorf("UpdateLeaf(%d, %d bytes) succeeded", tc.i, len(tc.leaf))
+		}
+	}
+	if err := tree.UpdateLeaf(2, make([]byte, 4)); err != nil {
+		t.Errorf("growing the last leaf: %v", err)
+	}
+}
+
+// TestParams checks that invalid parameters are rejected.
+func TestParams(t *testing.T) {
+	for _, p := range []Params{
+		{LeafSize: 0, Fanout: 2},
+		{LeafSize: 1, Fanout: 1},
+		{LeafSize: 1, Fanout: 2, Height: 1},
+	} {
+		if _, err := New(p); err == nil {
+			t.Errorf("New(%+v) succeeded", p)
+		}
+		if _, err := NewTree(p, bytes.NewReader(nil)); err == nil {
+			t.Errorf("NewTree(%+v) succeeded", p)
+		}
+	}
+}
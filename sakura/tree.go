@@ -0,0 +1,136 @@
+package sakura
+
+import (
+	"errors"
+	"io"
+)
+
+var (
+	errLeafIndex  = errors.New("sakura: leaf index out of range")
+	errLeafLength = errors.New("sakura: leaf has the wrong length")
+)
+
+// A Tree holds the chaining values of all the nodes of the tree of a
+// message, so that its hash can be recomputed when a leaf changes by
+// rehashing only the nodes on the path from that leaf to the root.
+type Tree struct {
+	p Params
+
+	// single is the message, when it fits in a single node.
+	single []byte
+
+	// levels holds the chaining values of the leaves and of the inner
+	// nodes, level by level from the leaves up to the level below the
+	// root.
+	levels [][][Size]byte
+}
+
+// NewTree reads a message from r until EOF and returns its tree with
+// parameters p. It returns an error if the parameters are invalid or r
+// fails.
+func NewTree(p Params, r io.Reader) (*Tree, error) {
+	if err := p.check(); err != nil {
+		return nil, err
+	}
+	t := &Tree{p: p}
+
+	var leaves [][Size]byte
+	leaf := make([]byte, p.LeafSize)
+	next := make([]byte, p.LeafSize)
+	n, err := io.ReadFull(r, leaf)
+	for {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		// The leaf is full; it is the single node unless more follows.
+		var m int
+		m, err = io.ReadFull(r, next)
+		if m == 0 && err == io.EOF {
+			break
+		}
+		var cv [Size]byte
+		hashMessage(&cv, leaf, false)
+		leaves = append(leaves, cv)
+		leaf, next, n = next, leaf, m
+	}
+	if leaves == nil {
+		t.single = append([]byte(nil), leaf[:n]...)
+		return t, nil
+	}
+	var cv [Size]byte
+	hashMessage(&cv, leaf[:n], false)
+	t.levels = [][][Size]byte{append(leaves, cv)}
+
+	// Group the chaining values level by level up to the root.
+	for k := 0; !p.isRootLevel(k+1, len(t.levels[k]), false); k++ {
+		below := t.levels[k]
+		up := make([][Size]byte, (len(below)+p.Fanout-1)/p.Fanout)
+		for i := range up {
+			t.hashInner(&up[i], below, i)
+		}
+		t.levels = append(t.levels, up)
+	}
+	return t, nil
+}
+
+// hashInner hashes the i-th group of Fanout chaining values of below into
+// cv.
+func (t *Tree) hashInner(cv *[Size]byte, below [][Size]byte, i int) {
+	start, end := i*t.p.Fanout, (i+1)*t.p.Fanout
+	if end > len(below) {
+		end = len(below)
+	}
+	hashCVs(cv, below[start:end], false)
+}
+
+// NumLeaves returns the number of leaves of the tree. A message of at
+// most one leaf has a single leaf, which is the root.
+func (t *Tree) NumLeaves() int {
+	if t.levels == nil {
+		return 1
+	}
+	return len(t.levels[0])
+}
+
+// Sum appends the hash of the message to b.
+func (t *Tree) Sum(b []byte) []byte {
+	var out [Size]byte
+	if t.levels == nil {
+		hashMessage(&out, t.single, true)
+	} else {
+		hashCVs(&out, t.levels[len(t.levels)-1], true)
+	}
+	return append(b, out[:]...)
+}
+
+// UpdateLeaf replaces the content of the i-th leaf with leaf and updates
+// the nodes above it. All leaves but the last must have LeafSize bytes;
+// the last one must have between one and LeafSize bytes, or at most
+// LeafSize bytes if it is the only one. It returns an error, and changes
+// nothing, if i is out of range or leaf has the wrong length.
+func (t *Tree) UpdateLeaf(i int, leaf []byte) error {
+	n := t.NumLeaves()
+	if i < 0 || i >= n {
+		return errLeafIndex
+	}
+	switch {
+	case i < n-1 && len(leaf) != t.p.LeafSize,
+		i == n-1 && len(leaf) > t.p.LeafSize,
+		i == n-1 && len(leaf) == 0 && n > 1:
+		return errLeafLength
+	}
+	if t.levels == nil {
+		t.single = append(t.single[:0], leaf...)
+		return nil
+	}
+
+	hashMessage(&t.levels[0][i], leaf, false)
+	for k := 1; k < len(t.levels); k++ {
+		i /= t.p.Fanout
+		t.hashInner(&t.levels[k][i], t.levels[k-1], i)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+// This file provides functions hashing several independent messages at
+// once with the four-way permutation. Their results are identical to those
+// of the corresponding functions on single messages.
+
+import (
+	"encoding/binary"
+)
+
+// batchLane is the progress of the hashing of one of the messages of a
+// four-way batch.
+type batchLane struct {
+	in        []byte // input not yet absorbed
+	out       []byte // output not yet squeezed
+	squeezing bool
+}
+
+// sumX4 hashes each in[j] into out[j] with the sponge function of the
+// given rate and domain separation byte, using the four-way permutation.
+// States with an empty out[j] are unused, and are permuted idly.
+func sumX4(rate int, dsbyte byte, out, in *[4][]byte) {
+	var a [100]uint64
+	var lanes [4]batchLane
+	var block [maxRate]byte
+	for j := range lanes {
+		lanes[j] = batchLane{in: in[j], out: out[j]}
+	}
+
+	for {
+		busy := false
+		for j := range lanes {
+			l := &lanes[j]
+			if len(l.out) == 0 {
+				continue
+			}
+			busy = true
+			if l.squeezing {
+				continue
+			}
+			if len(l.in) >= rate {
+				xorInX4(&a, j, l.in[:rate])
+				l.in = l.in[rate:]
+				continue
+			}
+			// Absorb the last block, padded as in padAndPermute.
+			n := copy(block[:], l.in)
+			block[n] = dsbyte
+			for i := n + 1; i < rate; i++ {
+				block[i] = 0
+			}
+			block[rate-1] ^= 0x80
+			xorInX4(&a, j, block[:rate])
+			l.squeezing = true
+		}
+		if !busy {
+			return
+		}
+
+		keccakP1600x4(&a, len(rc))
+
+		for j := range lanes {
+			l := &lanes[j]
+			if l.squeezing && len(l.out) > 0 {
+				n := rate
+				if n > len(l.out) {
+					n = len(l.out)
+				}
+				copyOutX4(&a, j, l.out[:n])
+				l.out = l.out[n:]
+			}
+		}
+	}
+}
+
+// xorInX4 xors a block of rate bytes into state j of a.
+func xorInX4(a *[100]uint64, j int, buf []byte) {
+	i := 0
+	for ; len(buf) >= 8; i++ {
+		a[4*i+j] ^= binary.LittleEndian.Uint64(buf)
+		buf = buf[8:]
+	}
+	for k, v := range buf {
+		a[4*i+j] ^= uint64(v) << uint(8*k)
+	}
+}
+
+// copyOutX4 copies the first len(b) bytes of state j of a into b.
+func copyOutX4(a *[100]uint64, j int, b []byte) {
+	i := 0
+	for ; len(b) >= 8; i++ {
+		binary.LittleEndian.PutUint64(b, a[4*i+j])
+		b = b[8:]
+	}
+	for k := range b {
+		b[k] = byte(a[4*i+j] >> uint(8*k))
+	}
+}
+
+// sumBatch hashes each data[i] into hashes[i], four at a time, and the
+// remaining messages one by one with sum. It panics if hashes and data
+// have different lengths.
+func sumBatch(rate int, dsbyte byte, hashes, data [][]byte, sum func(hash, data []byte)) {
+	if len(hashes) != len(data) {
+		panic("sha3: batch with different numbers of messages and digests")
+	}
+	for len(data) >= 4 {
+		var out, in [4][]byte
+		copy(out[:], hashes)
+		copy(in[:], data)
+		sumX4(rate, dsbyte, &out, &in)
+		hashes, data = hashes[4:], data[4:]
+	}
+	for i := range data {
+		sum(hashes[i], data[i])
+	}
+}
+
+// Sum256x4 returns the SHA3-256 digests of four messages, computed
+// together.
+func Sum256x4(data [4][]byte) (digests [4][32]byte) {
+	var out [4][]byte
+	for j := range out {
+		out[j] = digests[j][:]
+	}
+	sumX4(136, 0x06, &out, &data)
+	return
+}
+
+// Sum256Batch writes the SHA3-256 digest of each data[i] into digests[i].
+// It panics if digests and data have different lengths.
+func Sum256Batch(digests [][32]byte, data [][]byte) {
+	hashes := make([][]byte, len(digests))
+	for i := range digests {
+		hashes[i] = digests[i][:]
+	}
+	sumBatch(136, 0x06, hashes, data, func(hash, data []byte) {
+		h := New256()
+		h.Write(data)
+		h.Sum(hash[:0])
+	})
+}
+
+// ShakeSum128Batch fills each hashes[i] with the SHAKE128 output of
+// data[i], as ShakeSum128 does. It panics if hashes and data have
+// different lengths.
+func ShakeSum128Batch(hashes, data [][]byte) {
+	sumBatch(rate128, dsbyteShake, hashes, data, ShakeSum128)
+}
+
+// ShakeSum256Batch fills each hashes[i] with the SHAKE256 output of
+// data[i], as ShakeSum256 does. It panics if hashes and data have
+// different lengths.
+func ShakeSum256Batch(hashes, data [][]byte) {
+	sumBatch(rate256, dsbyteShake, hashes, data, ShakeSum256)
+}
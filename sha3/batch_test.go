@@ -0,0 +1,118 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestKeccakP1600x4(t *testing.T) {
+	var a [100]uint64
+	var s [4][25]uint64
+	for i := range a {
+		a[i] = uint64(i) * 0x9e3779b97f4a7c15
+		s[i%4][i/4] = a[i]
+	}
+	for _, rounds := range []int{24, 12, 1} {
+		keccakP1600x4(&a, rounds)
+		for j := range s {
+			keccakP1600(&s[j], rounds)
+			for i := range s[j] {
+				if a[4*i+j] != s[j][i] {
+					t.Fatalf("%d rounds: lane %d of state %d is %016x, want %016x", rounds, i, j, a[4*i+j], s[j][i])
+				}
+			}
+		}
+	}
+}
+
+func TestSum256x4(t *testing.T) {
+	msg := sequentialBytes(1000)
+	for _, lens := range [][4]int{{0, 0, 0, 0}, {1, 135, 136, 137}, {1000, 3, 272, 0}} {
+		var data [4][]byte
+		for j, n := range lens {
+			data[j] = msg[:n]
+		}
+		got := Sum256x4(data)
+		for j := range data {
+			if want := Sum256(data[j]); got[j] != want {
+				t.Errorf("message of %d bytes: got %x, want %x", lens[j], got[j], want)
+			}
+		}
+	}
+}
+
+func TestSum256Batch(t *testing.T) {
+	msg := sequentialBytes(1000)
+	data := make([][]byte, 11)
+	for i := range data {
+		data[i] = msg[:i*91]
+	}
+	digests := make([][32]byte, len(data))
+	Sum256Batch(digests, data)
+	for i := range data {
+		if want := Sum256(data[i]); digests[i] != want {
+			t.Errorf("message of %d bytes: got %x, want %x", len(data[i]), digests[i], want)
+		}
+	}
+}
+
+func TestShakeSumBatch(t *testing.T) {
+	msg := sequentialBytes(1000)
+	for _, tc := range []struct {
+		name  string
+		batch func(hashes, data [][]byte)
+		sum   func(hash, data []byte)
+	}{
+		{"SHAKE128", ShakeSum128Batch, ShakeSum128},
+		{"SHAKE256", ShakeSum256Batch, ShakeSum256},
+	} {
+		data := make([][]byte, 9)
+		hashes := make([][]byte, len(data))
+		for i := range data {
+			data[i] = msg[:(i*137)%len(msg)]
+			hashes[i] = make([]byte, (i*61)%400)
+		}
+		tc.batch(hashes, data)
+		for i := range data {
+			want := make([]byte, len(hashes[i]))
+			tc.sum(want, data[i])
+			if !bytes.Equal(hashes[i], want) {
+				t.Errorf("%s of %d bytes, %d bytes of output: got %x, want %x",
+					tc.name, len(data[i]), len(want), hashes[i], want)
+			}
+		}
+	}
+}
+
+func TestBatchLengthMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("ShakeSum256Batch with fewer hashes than messages did not panic")
+		}
+	}()
+	ShakeSum256Batch(make([][]byte, 3), make([][]byte, 4))
+}
+
+func BenchmarkSum256x4_64(b *testing.B) {
+	data := [4][]byte{
+		sequentialBytes(64), sequentialBytes(64), sequentialBytes(64), sequentialBytes(64),
+	}
+	b.SetBytes(4 * 64)
+	for i := 0; i < b.N; i++ {
+		Sum256x4(data)
+	}
+}
+
+func BenchmarkSum256_64x4(b *testing.B) {
+	data := sequentialBytes(64)
+	b.SetBytes(4 * 64)
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < 4; j++ {
+			Sum256(data)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+// This file provides messages and outputs whose length is not a multiple
+// of eight bits. FIPS 202 defines the SHA-3 and SHAKE functions on bit
+// strings, which are converted to and from bytes with the least
+// significant bit of each byte first: the five-bit message 11001 is the
+// byte 0x13, for instance.
+
+// A BitWriter absorbs a message whose length is not necessarily a
+// multiple of eight bits. The SHA-3 hashes returned by New224, New256,
+// New384 and New512, the SHAKE and cSHAKE instances and the legacy Keccak
+// hashes implement it, as do the TurboSHAKE instances.
+type BitWriter interface {
+	// WriteBits absorbs the first nbits bits of p: the first nbits/8
+	// bytes whole, followed by the nbits%8 least significant bits of the
+	// next byte. A partial byte ends the message: after it, Write and
+	// WriteBits panic until the hash is reset. WriteBits panics if nbits
+	// is negative or greater than 8*len(p).
+	WriteBits(p []byte, nbits int)
+}
+
+// A BitReader produces an output whose length is not necessarily a
+// multiple of eight bits. The SHAKE and cSHAKE instances implement it.
+type BitReader interface {
+	// ReadBits squeezes nbits bits of output into the first (nbits+7)/8
+	// bytes of out; the unused most significant bits of the last byte
+	// are cleared, and the rest of the last byte of output is discarded.
+	// It panics if nbits is negative or greater than 8*len(out).
+	ReadBits(out []byte, nbits int)
+}
+
+// WriteBits implements BitWriter.
+func (d *state) WriteBits(p []byte, nbits int) {
+	if nbits < 0 || nbits > 8*len(p) {
+		panic("sha3: invalid bit length")
+	}
+	d.Write(p[:nbits/8])
+	if r := nbits % 8; r != 0 {
+		d.trailing = p[nbits/8] & (1<<uint(r) - 1)
+		d.ntrailing = r
+	}
+}
+
+// ReadBits implements BitReader.
+func (d *state) ReadBits(out []byte, nbits int) {
+	if nbits < 0 || nbits > 8*len(out) {
+		panic("sha3: invalid bit length")
+	}
+	out = out[:(nbits+7)/8]
+	d.Read(out)
+	if r := nbits % 8; r != 0 {
+		out[len(out)-1] &= 1<<uint(r) - 1
+	}
+}
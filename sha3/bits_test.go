@@ -0,0 +1,166 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// TestWriteBits checks messages whose length is not a multiple of eight
+// bits. The first two are the examples published by NIST for SHA3-256;
+// the others are taken from sequentialBytes and end where the padding
+// crosses a block boundary.
+func TestWriteBits(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		newH  func() ShakeHash
+		msg   []byte
+		nbits int
+		out   int // output length in bits
+		want  string
+	}{
+		{
+			"SHA3-256", func() ShakeHash { return New256().(*state) }, []byte{0x13}, 5, 256,
+			"7b0047cf5a456882363cbf0fb05322cf65f4b7059a46365e830132e3b5d957af",
+		},
+		{
+			"SHA3-256", func() ShakeHash { return New256().(*state) }, []byte{0x53, 0x58, 0x7b, 0x19}, 30, 256,
+			"c8242fef409e5ae9d1f1c857ae4dc624b92b19809f62aa8c07411c54a078b1d0",
+		},
+		{
+			// The first bit of the padding ends the first block.
+			"SHA3-256", func() ShakeHash { return New256().(*state) }, sequentialBytes(136), 1085, 256,
+			"6202459359647aa0e51ef66dc094b8c3cadd7b4cf25fbfc0efcfd9da343e2bf2",
+		},
+		{
+			// The domain separation bits straddle two blocks.
+			"SHA3-256", func() ShakeHash { return New256().(*state) }, sequentialBytes(136), 1087, 256,
+			"94a17fbcb133bdd8387119ac4ff332a9d9c0c8e87ed0f9e57595bb338feb6a2e",
+		},
+		{
+			"SHA3-224", func() ShakeHash { return New224().(*state) }, sequentialBytes(136), 1087, 224,
+			"5d8c7f411bc53207334531ccab64e248707356fcd583622759fa771d",
+		},
+		{
+			"SHA3-512", func() ShakeHash { return New512().(*state) }, sequentialBytes(72), 573, 512,
+			"d29031fdddf0a50d610b0e813424267cc4fc59580c98bb20ce35e0b3b1a88ca3651ed3a7d5653a890587089cd66cfb21627bb8d1d597b3b9f90a488ad6055d2e",
+		},
+		{
+			"SHAKE128", NewShake128, sequentialBytes(168), 1340, 256,
+			"2bd06c9224820f5dfc7b8286f317a32051401fe66b26ac53f904c004cd6a9f63",
+		},
+		{
+			"SHAKE128", NewShake128, []byte{0x00}, 4, 13,
+			"481f",
+		},
+		{
+			"SHAKE256", NewShake256, sequentialBytes(136), 1083, 257,
+			"b851c4b32f2eb82aa66c39856cd7e3529d833e29f723bb3278806d78fcec42e300",
+		},
+		{
+			"SHAKE256", NewShake256, []byte{0x00}, 7, 1,
+			"00",
+		},
+	} {
+		want, _ := hex.DecodeString(tc.want)
+
+		// Set the unused bits, which must be ignored.
+		msg := append([]byte(nil), tc.msg[:(tc.nbits+7)/8]...)
+		if r := tc.nbits % 8; r != 0 {
+			msg[len(msg)-1] |= 0xff << uint(r)
+		}
+
+		h := tc.newH()
+		h.Write(msg[:3*len(msg)/4/8])
+		h.(BitWriter).WriteBits(msg[3*len(msg)/4/8:], tc.nbits-8*(3*len(msg)/4/8))
+		got := bytes.Repeat([]byte{0xff}, len(want)+1)
+		h.(BitReader).ReadBits(got, tc.out)
+		if !bytes.Equal(got[:len(want)], want) {
+			t.Errorf("%s(%d bits): got %x, want %x", tc.name, tc.nbits, got[:len(want)], want)
+		}
+		if got[len(want)] != 0xff {
+			t.Errorf("%s(%d bits): ReadBits wrote past its output", tc.name, tc.nbits)
+		}
+	}
+}
+
+// TestWriteBitsWholeBytes checks that messages and outputs of whole bytes
+// are unaffected by going through WriteBits and ReadBits.
+func TestWriteBitsWholeBytes(t *testing.T) {
+	msg := sequentialBytes(300)
+	for _, n := range []int{0, 1, 135, 136, 137, 300} {
+		h := NewShake256()
+		h.Write(msg[:n])
+		want := make([]byte, 100)
+		h.Read(want)
+
+		h.Reset()
+		h.(BitWriter).WriteBits(msg, 8*n)
+		h.Write(nil) // a whole number of bytes does not end the message
+		got := make([]byte, 100)
+		h.(BitReader).ReadBits(got, 800)
+		if !bytes.Equal(got, want) {
+			t.Errorf("%d bytes: got %x, want %x", n, got, want)
+		}
+	}
+}
+
+// TestWriteBitsSum checks that Sum, Clone and Reset handle a partial byte.
+func TestWriteBitsSum(t *testing.T) {
+	h := New256()
+	h.(BitWriter).WriteBits([]byte{0x13}, 5)
+	want, _ := hex.DecodeString("7b0047cf5a456882363cbf0fb05322cf65f4b7059a46365e830132e3b5d957af")
+	if got := h.(*state).Clone().(*state).Sum(nil); !bytes.Equal(got, want) {
+		t.Errorf("Clone: got %x, want %x", got, want)
+	}
+	if got := h.Sum(nil); !bytes.Equal(got, want) {
+		t.Errorf("got %x, want %x", got, want)
+	}
+	if got := h.Sum(nil); !bytes.Equal(got, want) {
+		t.Errorf("second Sum: got %x, want %x", got, want)
+	}
+	if _, err := h.(*state).MarshalBinary(); err == nil {
+		t.Error("MarshalBinary succeeded with a partial byte")
+	}
+
+	h.Reset()
+	if got, want := h.Sum(nil), Sum256(nil); !bytes.Equal(got, want[:]) {
+		t.Errorf("after Reset: got %x, want %x", got, want)
+	}
+}
+
+// TestWriteBitsPanics checks that misuse of WriteBits and ReadBits panics.
+func TestWriteBitsPanics(t *testing.T) {
+	mustPanic := func(name string, f func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s did not panic", name)
+			}
+		}()
+		f()
+	}
+	mustPanic("Write after partial byte", func() {
+		h := NewShake128()
+		h.(BitWriter).WriteBits([]byte{0}, 3)
+		h.Write([]byte{0})
+	})
+	mustPanic("WriteBits after partial byte", func() {
+		h := NewShake128()
+		h.(BitWriter).WriteBits([]byte{0}, 3)
+		h.(BitWriter).WriteBits([]byte{0}, 3)
+	})
+	mustPanic("WriteBits past the end of p", func() {
+		NewShake128().(BitWriter).WriteBits([]byte{0}, 9)
+	})
+	mustPanic("negative ReadBits", func() {
+		NewShake128().(BitReader).ReadBits(make([]byte, 1), -1)
+	})
+	mustPanic("ReadBits past the end of out", func() {
+		NewShake128().(BitReader).ReadBits(make([]byte, 1), 9)
+	})
+}
@@ -0,0 +1,470 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+// This file is a harness for the test vectors published by NIST: the
+// CAVP response files (.rsp) of the SHA-3 Validation System, and the
+// vector sets of the ACVP server in JSON. By default it runs the excerpts
+// of the NIST files in testdata/cavp and testdata/acvp; the full files,
+// downloaded from NIST, are run with
+//
+//	go test -run 'CAVP|ACVP' -cavp /path/to/rsp -acvp /path/to/json
+//
+// testdata/cavp-extra and testdata/acvp-extra hold further cases in the
+// same formats, computed independently of this package rather than taken
+// from NIST, for the tests of which no excerpt is bundled. They are always
+// run. testdata/README lists the sources of the files.
+//
+// Bit strings whose length is not a multiple of eight are written in both
+// formats with their partial last byte left-aligned: its bits are the
+// most significant ones, and the low bits are zero padding. WriteBits
+// takes them in the low bits, so the last byte is shifted right with
+// rightAlignLastByte before hashing. The other bytes are used unchanged.
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var (
+	cavpDir = flag.String("cavp", filepath.Join("testdata", "cavp"), "directory of NIST CAVP SHA-3 response files")
+	acvpDir = flag.String("acvp", filepath.Join("testdata", "acvp"), "directory of NIST ACVP SHA-3 vector sets")
+)
+
+// The directories of the cases that do not come from NIST.
+var (
+	cavpExtraDir = filepath.Join("testdata", "cavp-extra")
+	acvpExtraDir = filepath.Join("testdata", "acvp-extra")
+)
+
+// vectorFiles returns the files matching pattern in each of dirs.
+func vectorFiles(pattern string, dirs ...string) []string {
+	var files []string
+	for _, dir := range dirs {
+		paths, _ := filepath.Glob(filepath.Join(dir, pattern))
+		files = append(files, paths...)
+	}
+	return files
+}
+
+// vectorName returns the name of the subtest running the file at path:
+// the name of its directory followed by its own.
+func vectorName(path string) string {
+	return filepath.Base(filepath.Dir(path)) + "/" + filepath.Base(path)
+}
+
+// cavpHash returns a new instance of the function named name, in the
+// spelling of either the CAVP file names (SHA3_256, SHAKE128) or the ACVP
+// algorithm names (SHA3-256, SHAKE-128), or nil if there is none.
+func cavpHash(name string) *state {
+	switch strings.Replace(strings.Replace(name, "_", "-", 1), "SHAKE-", "SHAKE", 1) {
+	case "SHA3-224":
+		return New224().(*state)
+	case "SHA3-256":
+		return New256().(*state)
+	case "SHA3-384":
+		return New384().(*state)
+	case "SHA3-512":
+		return New512().(*state)
+	case "SHAKE128":
+		return NewShake128().(*state)
+	case "SHAKE256":
+		return NewShake256().(*state)
+	}
+	return nil
+}
+
+// rightAlignLastByte returns a copy of the first (nbits+7)/8 bytes of b
+// in which a partial last byte, of nbits%8 bits held in its most
+// significant bits, is shifted down into its least significant bits. The
+// order of the bits is not changed.
+func rightAlignLastByte(b []byte, nbits int) []byte {
+	b = append([]byte(nil), b[:(nbits+7)/8]...)
+	if r := nbits % 8; r != 0 {
+		b[len(b)-1] >>= uint(8 - r)
+	}
+	return b
+}
+
+// leftAlignLastByte shifts a partial last byte of b, of nbits%8 bits
+// held in its least significant bits, up into its most significant bits,
+// in place. It is the inverse of rightAlignLastByte.
+func leftAlignLastByte(b []byte, nbits int) []byte {
+	if r := nbits % 8; r != 0 {
+		b[len(b)-1] <<= uint(8 - r)
+	}
+	return b
+}
+
+// cavpDigest returns outBits bits of the output of h for the message of
+// msgBits bits in msg, both in the order of the NIST vectors.
+func cavpDigest(h *state, msg []byte, msgBits, outBits int) []byte {
+	h.Reset()
+	h.WriteBits(rightAlignLastByte(msg, msgBits), msgBits)
+	out := make([]byte, (outBits+7)/8)
+	h.ReadBits(out, outBits)
+	return leftAlignLastByte(out, outBits)
+}
+
+// sha3Monte runs count checkpoints of the Monte Carlo test of the SHA-3
+// functions from seed, calling check with each.
+func sha3Monte(h *state, seed []byte, count int, check func(j int, md []byte)) {
+	md := seed
+	for j := 0; j < count; j++ {
+		for i := 0; i < 1000; i++ {
+			h.Reset()
+			h.Write(md)
+			md = h.Sum(md[:0:0])
+		}
+		check(j, md)
+	}
+}
+
+// shakeMonte runs count checkpoints of the Monte Carlo test of the SHAKE
+// functions from msg, with output lengths from minBits to maxBits, calling
+// check with each.
+func shakeMonte(h *state, msg []byte, minBits, maxBits, count int, check func(j int, out []byte)) {
+	minBytes, maxBytes := minBits/8, maxBits/8
+	outLen := maxBytes
+	out := msg
+	for j := 0; j < count; j++ {
+		for i := 0; i < 1000; i++ {
+			// The message is the first 128 bits of the last output,
+			// padded with zeros if it is shorter.
+			var m [16]byte
+			copy(m[:], out)
+			h.Reset()
+			h.Write(m[:])
+			out = make([]byte, outLen)
+			h.Read(out)
+			rightmost := int(out[len(out)-2])<<8 | int(out[len(out)-1])
+			outLen = minBytes + rightmost%(maxBytes-minBytes+1)
+		}
+		check(j, out)
+	}
+}
+
+// A cavpRecord is a group of "key = value" lines of a response file,
+// together with the bracketed parameters preceding it.
+type cavpRecord struct {
+	line   int // line number of the first line of the record
+	params map[string]string
+	values map[string]string
+}
+
+// parseCAVP returns the records of the response file at path.
+func parseCAVP(path string) ([]cavpRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []cavpRecord
+	params := map[string]string{}
+	var cur *cavpRecord
+	inParams := false // whether the last non-blank line was a parameter
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<26)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || line[0] == '#':
+			cur = nil
+		case line[0] == '[':
+			// Parameters following records start a new section.
+			if !inParams {
+				params = map[string]string{}
+				inParams = true
+			}
+			k, v, _ := strings.Cut(strings.Trim(line, "[]"), "=")
+			params[strings.TrimSpace(k)] = strings.TrimSpace(v)
+			cur = nil
+		default:
+			inParams = false
+			k, v, ok := strings.Cut(line, "=")
+			if !ok {
+				return nil, fmt.Errorf("%s:%d: malformed line %q", path, n, line)
+			}
+			if cur == nil {
+				records = append(records, cavpRecord{line: n, params: params, values: map[string]string{}})
+				cur = &records[len(records)-1]
+			}
+			cur.values[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return records, scanner.Err()
+}
+
+// atoi and unhex parse values of the vectors, reporting errors to t.
+func atoi(t *testing.T, where, s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		t.Errorf("%s: %v", where, err)
+	}
+	return n
+}
+
+func unhex(t *testing.T, where, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Errorf("%s: %v", where, err)
+	}
+	return b
+}
+
+// TestCAVP runs the CAVP response files in the directory given by -cavp
+// and in testdata/cavp-extra. The function is given by the start of the
+// file name and the test by its end: ShortMsg, LongMsg, Monte or
+// VariableOut.
+func TestCAVP(t *testing.T) {
+	files := vectorFiles("*.rsp", *cavpDir, cavpExtraDir)
+	if len(files) == 0 {
+		t.Skipf("no response files in %s or %s", *cavpDir, cavpExtraDir)
+	}
+	for _, path := range files {
+		path := path
+		t.Run(vectorName(path), func(t *testing.T) {
+			records, err := parseCAVP(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			name := filepath.Base(path)
+			var h *state
+			for _, test := range []string{"ShortMsg", "LongMsg", "Monte", "VariableOut"} {
+				if i := strings.Index(name, test); i > 0 {
+					h = cavpHash(name[:i])
+				}
+			}
+			if h == nil {
+				t.Fatalf("unknown function or test in file name %s", name)
+			}
+			if strings.Contains(name, "Monte") {
+				runCAVPMonte(t, h, records)
+			} else {
+				runCAVPMsg(t, h, records)
+			}
+		})
+	}
+}
+
+// runCAVPMsg runs the records of the ShortMsg, LongMsg and VariableOut
+// tests, which give a message and the corresponding output.
+func runCAVPMsg(t *testing.T, h *state, records []cavpRecord) {
+	for _, r := range records {
+		where := fmt.Sprintf("line %d", r.line)
+		var msgBits int
+		if l, ok := r.values["Len"]; ok {
+			msgBits = atoi(t, where, l)
+		} else {
+			msgBits = atoi(t, where, r.params["Input Length"])
+		}
+		msg := unhex(t, where, r.values["Msg"])
+		want, ok := r.values["MD"]
+		if !ok {
+			want = r.values["Output"]
+		}
+		outBits := 4 * len(want)
+		if l, ok := r.values["Outputlen"]; ok {
+			outBits = atoi(t, where, l)
+		} else if l, ok := r.params["Outputlen"]; ok {
+			outBits = atoi(t, where, l)
+		}
+		if 8*len(msg) < msgBits || 8*len(want)/2 < outBits {
+			t.Errorf("%s: values shorter than their lengths", where)
+			continue
+		}
+		got := hex.EncodeToString(cavpDigest(h, msg, msgBits, outBits))
+		if got != strings.ToLower(want) {
+			t.Errorf("%s: Len = %d: got %s, want %s", where, msgBits, got, want)
+		}
+	}
+}
+
+// runCAVPMonte runs the records of the Monte Carlo tests: the seed,
+// followed by the outputs at each checkpoint.
+func runCAVPMonte(t *testing.T, h *state, records []cavpRecord) {
+	if len(records) == 0 {
+		return
+	}
+	seed := records[0]
+	want := records[1:]
+	where := fmt.Sprintf("line %d", seed.line)
+	check := func(j int, got []byte) {
+		r := want[j]
+		key := "MD"
+		if h.outputLen == 0 {
+			key = "Output"
+		}
+		if w := r.values[key]; hex.EncodeToString(got) != strings.ToLower(w) {
+			t.Errorf("line %d: COUNT = %d: got %x, want %s", r.line, j, got, w)
+		}
+	}
+	if h.outputLen != 0 {
+		sha3Monte(h, unhex(t, where, seed.values["Seed"]), len(want), check)
+	} else {
+		minBits := atoi(t, where, seed.params["Minimum Output Length (bits)"])
+		maxBits := atoi(t, where, seed.params["Maximum Output Length (bits)"])
+		shakeMonte(h, unhex(t, where, seed.values["Msg"]), minBits, maxBits, len(want), check)
+	}
+}
+
+// acvpVectorSet is the part of an ACVP vector set, including the expected
+// results, used by TestACVP.
+type acvpVectorSet struct {
+	VsID       int    `json:"vsId"`
+	Algorithm  string `json:"algorithm"`
+	TestGroups []struct {
+		TgID       int    `json:"tgId"`
+		TestType   string `json:"testType"`
+		MctVersion string `json:"mctVersion"`
+		MinOutLen  int    `json:"minOutLen"`
+		MaxOutLen  int    `json:"maxOutLen"`
+		Tests      []struct {
+			TcID     int    `json:"tcId"`
+			Msg      string `json:"msg"`
+			Len      int    `json:"len"`
+			OutLen   int    `json:"outLen"`
+			MD       string `json:"md"`
+			LargeMsg *struct {
+				Content            string `json:"content"`
+				ContentLength      int    `json:"contentLength"`
+				FullLength         int64  `json:"fullLength"`
+				ExpansionTechnique string `json:"expansionTechnique"`
+			} `json:"largeMsg"`
+			ResultsArray []struct {
+				MD     string `json:"md"`
+				OutLen int    `json:"outLen"`
+			} `json:"resultsArray"`
+		} `json:"tests"`
+	} `json:"testGroups"`
+}
+
+// parseACVP returns the vector sets in the file at path, which holds
+// either a vector set or an array of a version object and a vector set.
+func parseACVP(path string) ([]acvpVectorSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw []json.RawMessage
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+	} else {
+		raw = []json.RawMessage{data}
+	}
+	var sets []acvpVectorSet
+	for _, r := range raw {
+		var vs acvpVectorSet
+		if err := json.Unmarshal(r, &vs); err != nil {
+			return nil, err
+		}
+		if vs.Algorithm != "" {
+			sets = append(sets, vs)
+		}
+	}
+	return sets, nil
+}
+
+// TestACVP runs the ACVP vector sets, with their expected results, in the
+// directory given by -acvp and in testdata/acvp-extra.
+func TestACVP(t *testing.T) {
+	files := vectorFiles("*.json", *acvpDir, acvpExtraDir)
+	if len(files) == 0 {
+		t.Skipf("no vector sets in %s or %s", *acvpDir, acvpExtraDir)
+	}
+	for _, path := range files {
+		sets, err := parseACVP(path)
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+			continue
+		}
+		for _, vs := range sets {
+			vs := vs
+			t.Run(fmt.Sprintf("%s/%s/vsId=%d", vectorName(path), vs.Algorithm, vs.VsID), func(t *testing.T) {
+				runACVP(t, &vs)
+			})
+		}
+	}
+}
+
+func runACVP(t *testing.T, vs *acvpVectorSet) {
+	h := cavpHash(vs.Algorithm)
+	if h == nil {
+		t.Fatalf("unknown algorithm %s", vs.Algorithm)
+	}
+	for _, g := range vs.TestGroups {
+		for _, tc := range g.Tests {
+			where := fmt.Sprintf("tgId=%d tcId=%d", g.TgID, tc.TcID)
+			msg := unhex(t, where, tc.Msg)
+			outBits := tc.OutLen
+			if outBits == 0 {
+				outBits = 8 * h.outputLen
+			}
+			switch g.TestType {
+			case "AFT", "VOT":
+				if got := cavpDigest(h, msg, tc.Len, outBits); !bytes.Equal(got, unhex(t, where, tc.MD)) {
+					t.Errorf("%s: got %X, want %s", where, got, tc.MD)
+				}
+
+			case "MCT":
+				if g.MctVersion != "" && g.MctVersion != "standard" {
+					t.Errorf("%s: unsupported Monte Carlo test version %q", where, g.MctVersion)
+					continue
+				}
+				check := func(j int, got []byte) {
+					if want := tc.ResultsArray[j].MD; !bytes.Equal(got, unhex(t, where, want)) {
+						t.Errorf("%s: iteration %d: got %X, want %s", where, j, got, want)
+					}
+				}
+				if h.outputLen != 0 {
+					sha3Monte(h, msg, len(tc.ResultsArray), check)
+				} else {
+					shakeMonte(h, msg, g.MinOutLen, g.MaxOutLen, len(tc.ResultsArray), check)
+				}
+
+			case "LDT":
+				if testing.Short() {
+					t.Logf("%s: skipping large data test in short mode", where)
+					continue
+				}
+				lm := tc.LargeMsg
+				content := unhex(t, where, lm.Content)
+				if lm.ExpansionTechnique != "repeating" || lm.ContentLength != 8*len(content) || lm.FullLength%8 != 0 {
+					t.Errorf("%s: unsupported large message", where)
+					continue
+				}
+				h.Reset()
+				for n := lm.FullLength / 8; n > 0; {
+					c := content
+					if int64(len(c)) > n {
+						c = c[:n]
+					}
+					h.Write(c)
+					n -= int64(len(c))
+				}
+				got := make([]byte, outBits/8)
+				h.Read(got)
+				if !bytes.Equal(got, unhex(t, where, tc.MD)) {
+					t.Errorf("%s: got %X, want %s", where, got, tc.MD)
+				}
+
+			default:
+				t.Errorf("%s: unsupported test type %q", where, g.TestType)
+			}
+		}
+	}
+}
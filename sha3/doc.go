@@ -3,7 +3,10 @@
 // license that can be found in the LICENSE file.
 
 // Package sha3 implements the SHA-3 fixed-output-length hash functions and
-// the SHAKE variable-output-length hash functions defined by FIPS-202.
+// the SHAKE variable-output-length hash functions defined by FIPS-202, as
+// well as the cSHAKE, KMAC, TupleHash and ParallelHash functions defined by
+// NIST SP 800-185 and the TurboSHAKE and KangarooTwelve functions defined by
+// RFC 9861.
 //
 // Both types of hash function use the "sponge" construction and the Keccak
 // permutation. For a detailed specification see http://keccak.noekeon.org/
@@ -14,9 +17,11 @@
 // If you aren't sure what function you need, use SHAKE256 with at least 64
 // bytes of output.
 //
-// If you need a secret-key MAC (message authentication code), prepend the
-// secret key to the input, hash with SHAKE256 and read at least 32 bytes of
-// output.
+// If you need a secret-key MAC (message authentication code), use KMAC256
+// (see NewKMAC256) with a key of at least 32 bytes and at least 32 bytes of
+// output. KMAC is the MAC standardized in NIST SP 800-185; unlike a key
+// simply prepended to the input, it separates the key from the message
+// unambiguously and accepts a customization string.
 //
 //
 // Security strengths
@@ -0,0 +1,160 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+// This file provides a deterministic random bit generator built on
+// SHAKE256, in the style of the DRBGs of NIST SP 800-90A: it is seeded
+// with entropy, a nonce and a personalization string, is reseeded with
+// fresh entropy, and refuses to generate output when a reseed is due.
+//
+// The DRBG keeps a 64-byte key. Every operation hashes a label naming the
+// operation, the key and the inputs of the operation, each encoded with
+// encode_string from SP 800-185, with SHAKE256; it squeezes the new key
+// first and then the output, if any. The old key and the sponge are then
+// discarded, so that output already produced cannot be recomputed from a
+// later state of the generator.
+
+import (
+	"errors"
+)
+
+const (
+	// drbgKeySize is the size of the key of the DRBG, which is replaced
+	// after every operation.
+	drbgKeySize = 64
+
+	// DRBGMinEntropy is the minimum length of the entropy input given to
+	// NewDRBG and Reseed: 256 bits, the security strength of SHAKE256.
+	DRBGMinEntropy = 32
+
+	// DRBGMaxRequest is the largest output of a single call to Generate.
+	// Read splits longer outputs into several requests.
+	DRBGMaxRequest = 1 << 16
+
+	// drbgMaxReseedInterval is the default number of requests between
+	// reseeds, the largest allowed by SP 800-90A.
+	drbgMaxReseedInterval = 1 << 48
+)
+
+var (
+	// ErrReseedRequired is returned by Generate and Read when the DRBG
+	// has produced as many outputs as its reseed interval allows. It must
+	// be reseeded with Reseed before producing more.
+	ErrReseedRequired = errors.New("sha3: DRBG must be reseeded")
+
+	errDRBGEntropy = errors.New("sha3: DRBG entropy input too short")
+)
+
+// A DRBG is a deterministic random bit generator based on SHAKE256. Its
+// output is determined by its seed and the sequence of calls made to it,
+// so two DRBGs given the same inputs produce the same output; it must be
+// seeded with secret entropy to be used as a source of secret values.
+//
+// A DRBG is not safe for concurrent use.
+type DRBG struct {
+	key            [drbgKeySize]byte
+	reseedCounter  uint64 // the number of requests since the last reseed, plus one
+	reseedInterval uint64
+}
+
+// NewDRBG returns a new DRBG seeded with entropy, which must be secret and
+// at least DRBGMinEntropy bytes long, a nonce, which need not be secret
+// but should not repeat, and an optional personalization string that
+// separates this DRBG from others seeded alike.
+func NewDRBG(entropy, nonce, personalization []byte) (*DRBG, error) {
+	if len(entropy) < DRBGMinEntropy {
+		return nil, errDRBGEntropy
+	}
+	g := &DRBG{reseedCounter: 1, reseedInterval: drbgMaxReseedInterval}
+	g.update("instantiate", entropy, nonce, personalization).Wipe()
+	return g, nil
+}
+
+// update absorbs the label of an operation, the key and inputs into a new
+// SHAKE256 instance and replaces the key with its first output. It
+// returns the instance, from which the output of the operation can then
+// be squeezed.
+func (g *DRBG) update(label string, inputs ...[]byte) *state {
+	h := NewShake256().(*state)
+	h.Write(encodeString([]byte("sha3 DRBG " + label)))
+	writeEncodedString(h, g.key[:])
+	for _, in := range inputs {
+		writeEncodedString(h, in)
+	}
+	h.Read(g.key[:])
+	return h
+}
+
+// writeEncodedString absorbs encode_string(s) into h without copying s,
+// which may be secret, into a buffer that would have to be wiped.
+func writeEncodedString(h *state, s []byte) {
+	h.Write(leftEncode(uint64(len(s)) * 8))
+	h.Write(s)
+}
+
+// SetReseedInterval sets the number of requests after which the DRBG must
+// be reseeded. It panics if n is zero or greater than 2^48.
+func (g *DRBG) SetReseedInterval(n uint64) {
+	if n == 0 || n > drbgMaxReseedInterval {
+		panic("sha3: DRBG reseed interval out of range")
+	}
+	g.reseedInterval = n
+}
+
+// Reseed mixes fresh entropy, which must be at least DRBGMinEntropy bytes
+// long, and optional additional input into the DRBG, and restarts its
+// reseed interval.
+func (g *DRBG) Reseed(entropy, additionalInput []byte) error {
+	if len(entropy) < DRBGMinEntropy {
+		return errDRBGEntropy
+	}
+	g.update("reseed", entropy, additionalInput).Wipe()
+	g.reseedCounter = 1
+	return nil
+}
+
+// Generate fills out with output that also depends on additionalInput,
+// which may be nil. It returns ErrReseedRequired, and generates nothing,
+// if the DRBG must be reseeded first. It panics if out is longer than
+// DRBGMaxRequest bytes.
+func (g *DRBG) Generate(out, additionalInput []byte) error {
+	if len(out) > DRBGMaxRequest {
+		panic("sha3: DRBG request too long")
+	}
+	if g.reseedCounter > g.reseedInterval {
+		return ErrReseedRequired
+	}
+	h := g.update("generate", additionalInput)
+	h.Read(out)
+	h.Wipe()
+	g.reseedCounter++
+	return nil
+}
+
+// Read fills p with output, as a series of calls to Generate without
+// additional input of at most DRBGMaxRequest bytes each. Its output thus
+// depends on how it is split into calls to Read. It returns
+// ErrReseedRequired if the DRBG must be reseeded before p is filled.
+func (g *DRBG) Read(p []byte) (n int, err error) {
+	for n < len(p) {
+		todo := len(p) - n
+		if todo > DRBGMaxRequest {
+			todo = DRBGMaxRequest
+		}
+		if err := g.Generate(p[n:n+todo], nil); err != nil {
+			return n, err
+		}
+		n += todo
+	}
+	return n, nil
+}
+
+// Wipe overwrites the key of the DRBG with zeros. The DRBG must not be
+// used afterwards.
+func (g *DRBG) Wipe() {
+	for i := range g.key {
+		g.key[i] = 0
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func newTestDRBG(t *testing.T) *DRBG {
+	g, err := NewDRBG(sequentialBytes(32), sequentialBytes(48)[32:], []byte("personalization"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+// TestDRBG checks the DRBG against outputs computed independently from
+// the description of its construction.
+func TestDRBG(t *testing.T) {
+	g := newTestDRBG(t)
+	out := make([]byte, 32)
+	for i, tc := range []struct {
+		reseed     bool
+		additional string
+		want       string
+	}{
+		{false, "", "16e5508b4bcd1245cb3a05d925b20a06769d97a7dd9d2e50ab79b27e45a23784"},
+		{false, "additional", "fc16786ab2566b4597c150d943e2513042534cfda1a781cfac7f31d0905034e0"},
+		{true, "", "dad7d7b315a3612db4840ec9b0c7e80a1cb3d7e7d6ebd44b16a455fcdb2111e6"},
+	} {
+		if tc.reseed {
+			if err := g.Reseed(sequentialBytes(96)[64:], nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := g.Generate(out, []byte(tc.additional)); err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(out); got != tc.want {
+			t.Errorf("#%d: got %s, want %s", i, got, tc.want)
+		}
+	}
+}
+
+// TestDRBGRead checks that Read splits long outputs into requests, and
+// that each request gives new output.
+func TestDRBGRead(t *testing.T) {
+	g := newTestDRBG(t)
+	got := make([]byte, DRBGMaxRequest+100)
+	if n, err := g.Read(got); n != len(got) || err != nil {
+		t.Fatalf("Read: %d, %v", n, err)
+	}
+
+	g = newTestDRBG(t)
+	want := make([]byte, len(got))
+	g.Generate(want[:DRBGMaxRequest], nil)
+	g.Generate(want[DRBGMaxRequest:], nil)
+	if !bytes.Equal(got, want) {
+		t.Error("Read differs from Generate")
+	}
+	if bytes.Equal(want[:100], want[DRBGMaxRequest:]) {
+		t.Error("successive requests produced the same output")
+	}
+}
+
+// TestDRBGReseedInterval checks that the DRBG refuses to generate output
+// when a reseed is due.
+func TestDRBGReseedInterval(t *testing.T) {
+	g := newTestDRBG(t)
+	g.SetReseedInterval(2)
+	out := make([]byte, 16)
+	for i := 0; i < 2; i++ {
+		if err := g.Generate(out, nil); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+	if err := g.Generate(out, nil); err != ErrReseedRequired {
+		t.Fatalf("got %v, want ErrReseedRequired", err)
+	}
+	if n, err := g.Read(out); n != 0 || err != ErrReseedRequired {
+		t.Fatalf("Read: got %d, %v, want ErrReseedRequired", n, err)
+	}
+	if err := g.Reseed(sequentialBytes(32), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Generate(out, nil); err != nil {
+		t.Fatalf("after Reseed: %v", err)
+	}
+}
+
+// TestDRBGEntropy checks that short entropy inputs are rejected.
+func TestDRBGEntropy(t *testing.T) {
+	if _, err := NewDRBG(make([]byte, DRBGMinEntropy-1), nil, nil); err == nil {
+		t.Error("NewDRBG accepted short entropy")
+	}
+	if err := newTestDRBG(t).Reseed(make([]byte, DRBGMinEntropy-1), nil); err == nil {
+		t.Error("Reseed accepted short entropy")
+	}
+}
+
+// TestDRBGRatchet checks that each request replaces the key.
+func TestDRBGRatchet(t *testing.T) {
+	g := newTestDRBG(t)
+	key := g.key
+	g.Generate(nil, nil)
+	if g.key == key {
+		t.Error("key unchanged by Generate")
+	}
+}
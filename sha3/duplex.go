@@ -0,0 +1,70 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+// This file provides the duplex construction described in "Duplexing the
+// sponge: single-pass authenticated encryption and other applications"
+// by Bertoni, Daemen, Peeters and Van Assche
+// (http://keccak.noekeon.org/KeccakDuplex-0.2.pdf). Unlike a sponge, a
+// duplex object pads and absorbs its input and produces output at each
+// call, so that every output block depends on all the input given so far.
+
+// A Duplex is a duplex object on the Keccak-p[1600, rounds] permutation.
+// Each call to Duplex absorbs one block of input, padded with the domain
+// separation bits and the multi-rate padding, applies the permutation and
+// returns up to one block of output.
+type Duplex struct {
+	s *state
+}
+
+// NewDuplex returns a new Duplex with rate bytes of output per call. Its
+// parameters are those of NewSponge, and are validated in the same way.
+// The output of each call to the Duplex is the output of the Sponge with
+// the same parameters on the padded inputs of the previous calls followed
+// by the input of this call.
+func NewDuplex(rate int, dsbyte byte, rounds int) (*Duplex, error) {
+	s, err := newSpongeState(rate, dsbyte, rounds)
+	if err != nil {
+		return nil, err
+	}
+	return &Duplex{s: s}, nil
+}
+
+// Rate returns the maximum number of bytes of output per call.
+func (d *Duplex) Rate() int { return d.s.rate }
+
+// MaxInput returns the maximum number of bytes of input per call, one less
+// than the rate to leave room for the padding.
+func (d *Duplex) MaxInput() int { return d.s.rate - 1 }
+
+// Duplex absorbs in, applies the permutation and fills out with the first
+// len(out) bytes of the outer part of the state. It panics if in is longer
+// than MaxInput or out is longer than Rate.
+func (d *Duplex) Duplex(out, in []byte) {
+	d.duplex(out, in, d.s.dsbyte)
+}
+
+// duplex is Duplex with the domain separation bits given by dsbyte.
+func (d *Duplex) duplex(out, in []byte, dsbyte byte) {
+	if len(in) >= d.s.rate {
+		panic("sha3: duplex input longer than the rate")
+	}
+	if len(out) > d.s.rate {
+		panic("sha3: duplex output longer than the rate")
+	}
+	var block [maxRate]byte
+	n := copy(block[:], in)
+	block[n] = dsbyte
+	block[d.s.rate-1] ^= 0x80
+	d.s.xorIn(block[:d.s.rate])
+	d.s.keccak()
+	d.s.copyOut(out)
+}
+
+// Reset returns the Duplex to its initial, all-zero state.
+func (d *Duplex) Reset() { d.s.Reset() }
+
+// Clone returns a copy of the Duplex in its current state.
+func (d *Duplex) Clone() *Duplex { return &Duplex{s: d.s.clone()} }
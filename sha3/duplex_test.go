@@ -0,0 +1,228 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// spongeDuplex is a reference duplex object that computes each output
+// from scratch with a Sponge, following the duplex-sponge lemma: the
+// output of a call is the output of the sponge on the padded inputs of the
+// previous calls followed by the input of this call.
+type spongeDuplex struct {
+	rate   int
+	rounds int
+
+	// padded holds the padded inputs of the previous calls.
+	padded []byte
+}
+
+func (r *spongeDuplex) duplex(t *testing.T, out, in []byte, dsbyte byte) {
+	sp, err := NewSponge(r.rate, dsbyte, r.rounds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp.Absorb(r.padded)
+	sp.Absorb(in)
+	sp.Squeeze(out)
+
+	block := make([]byte, r.rate)
+	copy(block, in)
+	block[len(in)] = dsbyte
+	block[r.rate-1] ^= 0x80
+	r.padded = append(r.padded, block...)
+}
+
+func TestDuplexSpongeEquivalence(t *testing.T) {
+	for _, tc := range []struct {
+		rate   int
+		dsbyte byte
+		rounds int
+	}{
+		{rate256, 0x01, 24},
+		{rate128, 0x1f, 12},
+		{101, 0x06, 24},
+		{1, 0x01, 24},
+	} {
+		d, err := NewDuplex(tc.rate, tc.dsbyte, tc.rounds)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ref := &spongeDuplex{rate: tc.rate, rounds: tc.rounds}
+		msg := sequentialBytes(d.MaxInput())
+		for i := 0; i < 6; i++ {
+			in := msg[:(i*37)%(d.MaxInput()+1)]
+			got := make([]byte, (i*53)%(d.Rate()+1))
+			want := make([]byte, len(got))
+			d.Duplex(got, in)
+			ref.duplex(t, want, in, tc.dsbyte)
+			if !bytes.Equal(got, want) {
+				t.Errorf("rate %d, call %d: got %x, want %x", tc.rate, i, got, want)
+			}
+		}
+	}
+}
+
+func TestDuplexLimits(t *testing.T) {
+	d, err := NewDuplex(rate256, 0x01, 24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct{ out, in int }{{0, rate256}, {rate256 + 1, 0}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Duplex with %d bytes of output and %d bytes of input did not panic", tc.out, tc.in)
+				}
+			}()
+			d.Duplex(make([]byte, tc.out), make([]byte, tc.in))
+		}()
+	}
+}
+
+// referenceSpongeWrapSeal implements SpongeWrap as specified in the
+// duplex paper, on a reference duplex object.
+func referenceSpongeWrapSeal(t *testing.T, key, nonce, plaintext, ad []byte) []byte {
+	const rho = spongeWrapBlockSize
+	blocks := func(x []byte) [][]byte {
+		var b [][]byte
+		for len(x) > rho {
+			b = append(b, x[:rho])
+			x = x[rho:]
+		}
+		return append(b, x)
+	}
+	// frame appends the frame bit to the padding.
+	frame := func(bit byte) byte { return 0x02 | bit }
+
+	d := &spongeDuplex{rate: spongeWrapRate, rounds: 24}
+	K := blocks(key)
+	for i, k := range K {
+		bit := byte(1)
+		if i == len(K)-1 {
+			bit = 0
+		}
+		d.duplex(t, nil, k, frame(bit))
+	}
+
+	A := blocks(append(append([]byte{}, nonce...), ad...))
+	B := blocks(plaintext)
+	for _, a := range A[:len(A)-1] {
+		d.duplex(t, nil, a, frame(0))
+	}
+	Z := make([]byte, len(B[0]))
+	d.duplex(t, Z, A[len(A)-1], frame(1))
+	var C []byte
+	for i := 0; i < len(B)-1; i++ {
+		for j := range B[i] {
+			C = append(C, B[i][j]^Z[j])
+		}
+		Z = make([]byte, len(B[i+1]))
+		d.duplex(t, Z, B[i], frame(1))
+	}
+	for j := range B[len(B)-1] {
+		C = append(C, B[len(B)-1][j]^Z[j])
+	}
+	T := make([]byte, SpongeWrapTagSize)
+	d.duplex(t, T, B[len(B)-1], frame(0))
+	return append(C, T...)
+}
+
+func TestSpongeWrapReference(t *testing.T) {
+	key := sequentialBytes(SpongeWrapKeySize)
+	longKey := sequentialBytes(2*spongeWrapBlockSize + 1)
+	nonce := sequentialBytes(SpongeWrapNonceSize)
+	for _, k := range [][]byte{key, longKey} {
+		aead, err := NewSpongeWrap(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, adLen := range []int{0, 1, spongeWrapBlockSize - SpongeWrapNonceSize, 300} {
+			for _, ptLen := range []int{0, 1, spongeWrapBlockSize - 1, spongeWrapBlockSize, spongeWrapBlockSize + 1, 1000} {
+				pt := sequentialBytes(ptLen)
+				ad := sequentialBytes(adLen)
+				got := aead.Seal(nil, nonce, pt, ad)
+				want := referenceSpongeWrapSeal(t, k, nonce, pt, ad)
+				if !bytes.Equal(got, want) {
+					t.Errorf("key %d, ad %d, plaintext %d bytes: got %x, want %x", len(k), adLen, ptLen, got, want)
+				}
+			}
+		}
+	}
+}
+
+// spongeWrapVector was computed by referenceSpongeWrapSeal.
+var spongeWrapVector = struct {
+	key, nonce, plaintext, ad, sealed string
+}{
+	key:       "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+	nonce:     "000102030405060708090a0b0c0d0e0f1011121314151617",
+	plaintext: "54686520717569636b2062726f776e20666f78206a756d7073206f76657220746865206c617a7920646f67",
+	ad:        "616464",
+	sealed: "570294b731433f9bd749b9a2637b9afe342f9a551c57419c2a935863f0a8bf6b" +
+		"cac67cfe14951d13e52d344799e3be1ec37829c7b18163f29c2281efbc2563212703350584a2825c06a1a3",
+}
+
+func TestSpongeWrapVector(t *testing.T) {
+	v := spongeWrapVector
+	key, _ := hex.DecodeString(v.key)
+	nonce, _ := hex.DecodeString(v.nonce)
+	plaintext, _ := hex.DecodeString(v.plaintext)
+	ad, _ := hex.DecodeString(v.ad)
+	aead, err := NewSpongeWrap(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(aead.Seal(nil, nonce, plaintext, ad)); got != v.sealed {
+		t.Errorf("got %s, want %s", got, v.sealed)
+	}
+}
+
+func TestSpongeWrapOpen(t *testing.T) {
+	aead, err := NewSpongeWrap(sequentialBytes(SpongeWrapKeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, aead.NonceSize())
+	ad := []byte("additional data")
+	plaintext := sequentialBytes(300)
+
+	// Seal and Open in place.
+	buf := append([]byte(nil), plaintext...)
+	sealed := aead.Seal(buf[:0], nonce, buf, ad)
+	opened, err := aead.Open(sealed[:0], nonce, sealed, ad)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if !bytes.Equal(opened, plaintext) {
+		t.Fatalf("Open: got %x, want %x", opened, plaintext)
+	}
+
+	sealed = aead.Seal(nil, nonce, plaintext, ad)
+	otherNonce := append([]byte(nil), nonce...)
+	otherNonce[0] ^= 1
+	if _, err := aead.Open(nil, otherNonce, sealed, ad); err == nil {
+		t.Error("Open succeeded with the wrong nonce")
+	}
+	if _, err := aead.Open(nil, nonce, sealed, ad[1:]); err == nil {
+		t.Error("Open succeeded with the wrong additional data")
+	}
+	for _, i := range []int{0, 299, 300, len(sealed) - 1} {
+		tampered := append([]byte(nil), sealed...)
+		tampered[i] ^= 0x80
+		if _, err := aead.Open(nil, nonce, tampered, ad); err == nil {
+			t.Errorf("Open succeeded with byte %d modified", i)
+		}
+	}
+	if _, err := aead.Open(nil, nonce, sealed[:SpongeWrapTagSize-1], ad); err == nil {
+		t.Error("Open succeeded with a truncated ciphertext")
+	}
+	if _, err := NewSpongeWrap(make([]byte, SpongeWrapKeySize-1)); err == nil {
+		t.Error("NewSpongeWrap accepted a short key")
+	}
+}
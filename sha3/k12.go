@@ -0,0 +1,213 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+// This file provides KangarooTwelve, the tree hash function specified in
+// RFC 9861, in its KT128 and KT256 instances. KangarooTwelve cuts its
+// input into chunks of 8 KiB. The chunks after the first are hashed
+// independently, and thus in parallel, into chaining values by
+// TurboSHAKE, the 12-round variant of SHAKE; the first chunk and the
+// chaining values then form the final node of the tree, which is hashed
+// by TurboSHAKE into the output. The nodes are coded according to
+// Sakura (http://keccak.noekeon.org/Sakura.pdf), so that the output is
+// equivalent to a single TurboSHAKE call for inputs of at most one chunk.
+
+import (
+	"runtime"
+)
+
+const (
+	// k12ChunkSize is the size of the chunks hashed into each node.
+	k12ChunkSize = 8192
+
+	// Domain separation values for the nodes of the KangarooTwelve tree.
+	// They contain the Sakura suffix of the node and the first one bit of
+	// the padding: 11 for the only node of a single-node tree, 110 for
+	// leaves and 01 for a final node with kids.
+	dsbyteK12Single = 0x07
+	dsbyteK12Leaf   = 0x0b
+	dsbyteK12Final  = 0x06
+)
+
+// k12FinalNodeMarker follows the first chunk in a final node with kids:
+// the Sakura suffix 11 followed by zeros up to the next 64-bit boundary.
+var k12FinalNodeMarker = []byte{0x03, 0, 0, 0, 0, 0, 0, 0}
+
+// kangarooTwelve is a KT128 or KT256 instance.
+type kangarooTwelve struct {
+	final *state // TurboSHAKE instance hashing the final node
+
+	// customization is the customization string C, absorbed after the
+	// message together with its length.
+	customization []byte
+
+	rate    int // rate of the TurboSHAKE instances
+	cvLen   int // length of the chaining values in bytes
+	workers int
+
+	absorbed int    // bytes of the first chunk absorbed, up to k12ChunkSize
+	kids     bool   // whether the input is longer than one chunk
+	leaf     []byte // a partial chunk following the first one
+	nleaves  uint64 // the number of chaining values absorbed into final
+}
+
+func newKangarooTwelve(customization []byte, rate, cvLen int) *kangarooTwelve {
+	return &kangarooTwelve{
+		final:         newTurboShake(rate, dsbyteK12Single),
+		customization: append([]byte(nil), customization...),
+		rate:          rate,
+		cvLen:         cvLen,
+		workers:       runtime.GOMAXPROCS(0),
+	}
+}
+
+// NewKT128 creates a new KangarooTwelve KT128 variable-output-length
+// ShakeHash with customization string customization, which may be empty.
+// Its generic security strength is 128 bits against all attacks if at
+// least 32 bytes of its output are used.
+func NewKT128(customization []byte) ShakeHash {
+	return newKangarooTwelve(customization, rate128, 32)
+}
+
+// NewKT256 creates a new KangarooTwelve KT256 variable-output-length
+// ShakeHash with customization string customization, which may be empty.
+// Its generic security strength is 256 bits against all attacks if at
+// least 64 bytes of its output are used.
+func NewKT256(customization []byte) ShakeHash {
+	return newKangarooTwelve(customization, rate256, 64)
+}
+
+// k12LengthEncode returns the encoding of x as defined by length_encode in
+// RFC 9861: the big-endian bytes of x, with all leading zeros removed,
+// followed by their count. Unlike right_encode, zero is encoded as the
+// single byte 0x00.
+func k12LengthEncode(x uint64) []byte {
+	var b [9]byte
+	n := 0
+	for y := x; y > 0; y >>= 8 {
+		n++
+	}
+	for i := 0; i < n; i++ {
+		b[i] = byte(x >> uint(8*(n-1-i)))
+	}
+	b[n] = byte(n)
+	return b[:n+1]
+}
+
+// hashLeaf writes the chaining value of a chunk following the first one
+// into cv.
+func (k *kangarooTwelve) hashLeaf(cv, chunk []byte) {
+	leaf := newTurboShake(k.rate, dsbyteK12Leaf)
+	leaf.Write(chunk)
+	leaf.Read(cv)
+}
+
+// hashLeaves hashes the whole chunks in data and absorbs their chaining
+// values into the final node in order.
+func (k *kangarooTwelve) hashLeaves(data []byte) {
+	k.final.Write(hashLeaves(data, k12ChunkSize, k.cvLen, k.workers, k.hashLeaf))
+	k.nleaves += uint64(len(data) / k12ChunkSize)
+}
+
+// Write absorbs more data into the hash's state. It panics if input is
+// written to it after output has been read from it.
+func (k *kangarooTwelve) Write(p []byte) (written int, err error) {
+	if k.final.state != spongeAbsorbing {
+		panic("sha3: write to sponge after read")
+	}
+	k.write(p)
+	return len(p), nil
+}
+
+// write absorbs p, which is part of the message or of its suffix.
+func (k *kangarooTwelve) write(p []byte) {
+	if k.absorbed < k12ChunkSize {
+		// The first chunk goes straight into the final node.
+		todo := k12ChunkSize - k.absorbed
+		if todo > len(p) {
+			todo = len(p)
+		}
+		k.final.Write(p[:todo])
+		k.absorbed += todo
+		p = p[todo:]
+	}
+	if len(p) == 0 {
+		return
+	}
+	if !k.kids {
+		k.final.Write(k12FinalNodeMarker)
+		k.kids = true
+	}
+
+	if len(k.leaf) > 0 {
+		// Complete the partial chunk first.
+		todo := k12ChunkSize - len(k.leaf)
+		if todo > len(p) {
+			todo = len(p)
+		}
+		k.leaf = append(k.leaf, p[:todo]...)
+		p = p[todo:]
+		if len(k.leaf) < k12ChunkSize {
+			return
+		}
+		k.hashLeaves(k.leaf)
+		k.leaf = k.leaf[:0]
+	}
+
+	// Hash whole chunks straight from the input, a batch at a time.
+	for len(p) >= k12ChunkSize {
+		n := len(p) / k12ChunkSize
+		if limit := k.workers * maxBatchPerWorker; n > limit {
+			n = limit
+		}
+		k.hashLeaves(p[:n*k12ChunkSize])
+		p = p[n*k12ChunkSize:]
+	}
+
+	k.leaf = append(k.leaf, p...)
+}
+
+// Read squeezes an arbitrary number of bytes from the sponge. The first
+// call completes the input with the customization string and finishes the
+// tree; no more data can be written afterwards.
+func (k *kangarooTwelve) Read(out []byte) (n int, err error) {
+	if k.final.state == spongeAbsorbing {
+		k.write(k.customization)
+		k.write(k12LengthEncode(uint64(len(k.customization))))
+		if !k.kids {
+			k.final.dsbyte = dsbyteK12Single
+		} else {
+			if len(k.leaf) > 0 {
+				// The last chunk may be shorter than the others.
+				cv := make([]byte, k.cvLen)
+				k.hashLeaf(cv, k.leaf)
+				k.final.Write(cv)
+				k.nleaves++
+				k.leaf = k.leaf[:0]
+			}
+			k.final.Write(k12LengthEncode(k.nleaves))
+			k.final.Write([]byte{0xff, 0xff})
+			k.final.dsbyte = dsbyteK12Final
+		}
+	}
+	return k.final.Read(out)
+}
+
+// Clone returns a copy of the ShakeHash in its current state.
+func (k *kangarooTwelve) Clone() ShakeHash {
+	ret := *k
+	ret.final = k.final.clone()
+	ret.leaf = append([]byte(nil), k.leaf...)
+	return &ret
+}
+
+// Reset resets the ShakeHash to its initial state.
+func (k *kangarooTwelve) Reset() {
+	k.final.Reset()
+	k.absorbed = 0
+	k.kids = false
+	k.leaf = k.leaf[:0]
+	k.nleaves = 0
+}
@@ -0,0 +1,331 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+// Tests include the test vectors of RFC 9861, section 5.
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// ptn returns the RFC 9861 test pattern of n bytes: 0x00, 0x01, ..., 0xFA
+// repeated.
+func ptn(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i % 251)
+	}
+	return b
+}
+
+// ff returns n bytes 0xFF.
+func ff(n int) []byte {
+	return bytes.Repeat([]byte{0xff}, n)
+}
+
+// turboShakeVectors are the TurboSHAKE test vectors of RFC 9861. When the
+// output is long, only its last 32 bytes are given.
+var turboShakeVectors = []struct {
+	name          string
+	newTurboShake func(D byte) ShakeHash
+	message       []byte
+	D             byte
+	outputLen     int
+	digest        string
+}{
+	{"TurboSHAKE128", NewTurboShake128, nil, 0x1f, 32,
+		"1E415F1C5983AFF2169217277D17BB538CD945A397DDEC541F1CE41AF2C1B74C"},
+	{"TurboSHAKE128", NewTurboShake128, nil, 0x1f, 64,
+		"1E415F1C5983AFF2169217277D17BB538CD945A397DDEC541F1CE41AF2C1B74C" +
+			"3E8CCAE2A4DAE56C84A04C2385C03C15E8193BDF58737363321691C05462C8DF"},
+	{"TurboSHAKE128", NewTurboShake128, nil, 0x1f, 10032,
+		"A3B9B0385900CE761F22AED548E754DA10A5242D62E8C658E3F3A923A7555607"},
+	{"TurboSHAKE128", NewTurboShake128, ptn(1), 0x1f, 32,
+		"55CEDD6F60AF7BB29A4042AE832EF3F58DB7299F893EBB9247247D856958DAA9"},
+	{"TurboSHAKE128", NewTurboShake128, ptn(17), 0x1f, 32,
+		"9C97D036A3BAC819DB70EDE0CA554EC6E4C2A1A4FFBFD9EC269CA6A111161233"},
+	{"TurboSHAKE128", NewTurboShake128, ptn(17 * 17), 0x1f, 32,
+		"96C77C279E0126F7FC07C9B07F5CDAE1E0BE60BDBE10620040E75D7223A624D2"},
+	{"TurboSHAKE128", NewTurboShake128, ptn(17 * 17 * 17), 0x1f, 32,
+		"D4976EB56BCF118520582B709F73E1D6853E001FDAF80E1B13E0D0599D5FB372"},
+	{"TurboSHAKE128", NewTurboShake128, ptn(17 * 17 * 17 * 17), 0x1f, 32,
+		"DA67C7039E98BF530CF7A37830C6664E14CBAB7F540F58403B1B82951318EE5C"},
+	{"TurboSHAKE128", NewTurboShake128, ptn(17 * 17 * 17 * 17 * 17), 0x1f, 32,
+		"B97A906FBF83EF7C812517ABF3B2D0AEA0C4F60318CE11CF103925127F59EECD"},
+	{"TurboSHAKE128", NewTurboShake128, ff(0), 0x01, 32,
+		"868CBD53B078205ABB85815D941F7D0376BFF5B8888A6A2D03483AFBAF83967F"},
+	{"TurboSHAKE128", NewTurboShake128, ff(1), 0x06, 32,
+		"8EC9C66465ED0D4A6C35D13506718D687A25CB05C74CCA1E42501ABD83874A67"},
+	{"TurboSHAKE128", NewTurboShake128, ff(3), 0x07, 32,
+		"B658576001CAD9B1E5F399A9F77723BBA05458042D68206F7252682DBA3663ED"},
+	{"TurboSHAKE128", NewTurboShake128, ff(7), 0x0b, 32,
+		"8DEEAA1AEC47CCEE569F659C21DFA8E112DB3CEE37B18178B2ACD805B799CC37"},
+	{"TurboSHAKE128", NewTurboShake128, ff(0), 0x06, 32,
+		"C79029306BFA2F17836A3D6516D5566340FEA6EB1A1139AD900B41243C494B37"},
+	{"TurboSHAKE128", NewTurboShake128, ff(1), 0x30, 32,
+		"553122E2135E363C3292BED2C6421FA232BAB03DAA07C7D6636603286506325B"},
+	{"TurboSHAKE128", NewTurboShake128, ff(3), 0x7f, 32,
+		"16274CC656D44CEFD422395D0F9053BDA6D28E122ABA15C765E5AD0E6EAF26F9"},
+	{"TurboSHAKE256", NewTurboShake256, nil, 0x1f, 64,
+		"367A329DAFEA871C7802EC67F905AE13C57695DC2C6663C61035F59A18F8E7DB" +
+			"11EDC0E12E91EA60EB6B32DF06DD7F002FBAFABB6E13EC1CC20D995547600DB0"},
+	{"TurboSHAKE256", NewTurboShake256, nil, 0x1f, 128,
+		"F5717C743A7A78BF0B72ED4B9A7F51C7A0A06876F0D0526B68B8368CE563C770"},
+	{"TurboSHAKE256", NewTurboShake256, nil, 0x1f, 10064,
+		"34CED68CFC7BB16B5DB206E4583B904CCD2F0608616A58104D6F127C7BCEED93"},
+	{"TurboSHAKE256", NewTurboShake256, ptn(1), 0x1f, 64,
+		"3E1712F928F8EAF1054632B2AA0A246ED8B0C378728F60BC970410155C28820E" +
+			"90CC90D8A3006AA2372C5C5EA176B0682BF22BAE7467AC94F74D43D39B0482E2"},
+	{"TurboSHAKE256", NewTurboShake256, ptn(17), 0x1f, 64,
+		"B3BAB0300E6A191FBE6137939835923578794EA54843F5011090FA2F3780A9E5" +
+			"CB22C59D78B40A0FBFF9E672C0FBE0970BD2C845091C6044D687054DA5D8E9C7"},
+	{"TurboSHAKE256", NewTurboShake256, ptn(17 * 17), 0x1f, 64,
+		"66B810DB8E90780424C0847372FDC95710882FDE31C6DF75BEB9D4CD9305CFCA" +
+			"E35E7B83E8B7E6EB4B78605880116316FE2C078A09B94AD7B8213C0A738B65C0"},
+	{"TurboSHAKE256", NewTurboShake256, ptn(17 * 17 * 17), 0x1f, 64,
+		"C74EBC919A5B3B0DD1228185BA02D29EF442D69D3D4276A93EFE0BF9A16A7DC0" +
+			"CD4EABADAB8CD7A5EDD96695F5D360ABE09E2C6511A3EC397DA3B76B9E1674FB"},
+	{"TurboSHAKE256", NewTurboShake256, ptn(17 * 17 * 17 * 17), 0x1f, 64,
+		"02CC3A8897E6F4F6CCB6FD46631B1F5207B66C6DE9C7B55B2D1A23134A170AFD" +
+			"AC234EABA9A77CFF88C1F020B73724618C5687B362C430B248CD38647F848A1D"},
+	{"TurboSHAKE256", NewTurboShake256, ptn(17 * 17 * 17 * 17 * 17), 0x1f, 64,
+		"ADD53B06543E584B5823F626996AEE50FE45ED15F20243A7165485ACB4AA76B4" +
+			"FFDA75CEDF6D8CDC95C332BD56F4B986B58BB17D1778BFC1B1A97545CDF4EC9F"},
+	{"TurboSHAKE256", NewTurboShake256, ff(0), 0x01, 64,
+		"E3DD2DF0943BDE6D82E39EC36059F35CD76720E2DF38CC6B10B69FDDFCAA3A4A" +
+			"72FBBBE42C00CED7AA88E26D4675DD6E2C43C4413C4EA4D44BB170F03A981CAB"},
+	{"TurboSHAKE256", NewTurboShake256, ff(1), 0x06, 64,
+		"738D7B4E37D18B7F22AD1B5313E357E3DD7D07056A26A303C433FA3533455280" +
+			"F4F5A7D4F700EFB437FE6D281405E07BE32A0A972E22E63ADC1B090DAEFE004B"},
+	{"TurboSHAKE256", NewTurboShake256, ff(3), 0x07, 64,
+		"18B3B5B7061C2E67C1753A00E6AD7ED7BA1C906CF93EFB7092EAF27FBEEBB755" +
+			"AE6E292493C110E48D260028492B8E09B5500612B8F2578985DED5357D00EC67"},
+	{"TurboSHAKE256", NewTurboShake256, ff(7), 0x0b, 64,
+		"BB36764951EC97E9D85F7EE9A67A7718FC005CF42556BE79CE12C0BDE50E5736" +
+			"D6632B0D0DFB202D1BBB8FFE3DD74CB00834FA756CB03471BAB13A1E2C16B3C0"},
+	{"TurboSHAKE256", NewTurboShake256, ff(0), 0x06, 64,
+		"FF23DCCD62168F5A44465249A86DC10E8AAB4BD26A22DEBF2348020A831CDBE1" +
+			"2CDD36A7DDD31E71C01F7C97A0D4C3A0CC1B2121E6B7CEAB3887A4C9A5AF8B03"},
+	{"TurboSHAKE256", NewTurboShake256, ff(1), 0x30, 64,
+		"F3FE12873D34BCBB2E608779D6B70E7F86BEC7E90BF113CBD4FDD0C4E2F4625E" +
+			"148DD7EE1A52776CF77F240514D9CCFC3B5DDAB8EE255E39EE389072962C111A"},
+	{"TurboSHAKE256", NewTurboShake256, ff(3), 0x7f, 64,
+		"ABE569C1F77EC340F02705E7D37C9AB7E155516E4A6A150021D70B6FAC0BB40C" +
+			"069F9A9828A0D575CD99F9BAE435AB1ACF7ED9110BA97CE0388D074BAC768776"},
+}
+
+// TestTurboShakeVectors tests TurboSHAKE128 and TurboSHAKE256 against the
+// RFC 9861 test vectors.
+func TestTurboShakeVectors(t *testing.T) {
+	for _, v := range turboShakeVectors {
+		d := v.newTurboShake(v.D)
+		d.Write(v.message)
+		out := make([]byte, v.outputLen)
+		d.Read(out)
+		out = out[len(out)-len(v.digest)/2:]
+		if got := strings.ToUpper(hex.EncodeToString(out)); got != v.digest {
+			t.Errorf("%s(M=%d bytes, D=%#02x, L=%d):\ngot  %s\nwant %s",
+				v.name, len(v.message), v.D, v.outputLen, got, v.digest)
+		}
+	}
+}
+
+// TestTurboShakeDomainByte checks that the domain separation byte is
+// restricted to the range allowed by RFC 9861.
+func TestTurboShakeDomainByte(t *testing.T) {
+	for _, D := range []byte{0x00, 0x80, 0xff} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewTurboShake128(%#02x) did not panic", D)
+				}
+			}()
+			NewTurboShake128(D)
+		}()
+	}
+}
+
+// k12Vectors are the KangarooTwelve test vectors of RFC 9861. When the
+// output is long, only its last bytes are given.
+var k12Vectors = []struct {
+	name          string
+	newK12        func(customization []byte) ShakeHash
+	message       []byte
+	customization []byte
+	outputLen     int
+	digest        string
+}{
+	{"KT128", NewKT128, nil, nil, 32,
+		"1AC2D450FC3B4205D19DA7BFCA1B37513C0803577AC7167F06FE2CE1F0EF39E5"},
+	{"KT128", NewKT128, nil, nil, 64,
+		"1AC2D450FC3B4205D19DA7BFCA1B37513C0803577AC7167F06FE2CE1F0EF39E5" +
+			"4269C056B8C82E48276038B6D292966CC07A3D4645272E31FF38508139EB0A71"},
+	{"KT128", NewKT128, nil, nil, 10032,
+		"E8DC563642F7228C84684C898405D3A834799158C079B12880277A1D28E2FF6D"},
+	{"KT128", NewKT128, ptn(1), nil, 32,
+		"2BDA92450E8B147F8A7CB629E784A058EFCA7CF7D8218E02D345DFAA65244A1F"},
+	{"KT128", NewKT128, ptn(17), nil, 32,
+		"6BF75FA2239198DB4772E36478F8E19B0F371205F6A9A93A273F51DF37122888"},
+	{"KT128", NewKT128, ptn(17 * 17), nil, 32,
+		"0C315EBCDEDBF61426DE7DCF8FB725D1E74675D7F5327A5067F367B108ECB67C"},
+	{"KT128", NewKT128, ptn(17 * 17 * 17), nil, 32,
+		"CB552E2EC77D9910701D578B457DDF772C12E322E4EE7FE417F92C758F0D59D0"},
+	{"KT128", NewKT128, ptn(17 * 17 * 17 * 17), nil, 32,
+		"8701045E22205345FF4DDA05555CBB5C3AF1A771C2B89BAEF37DB43D9998B9FE"},
+	{"KT128", NewKT128, ptn(17 * 17 * 17 * 17 * 17), nil, 32,
+		"844D610933B1B9963CBDEB5AE3B6B05CC7CBD67CEEDF883EB678A0A8E0371682"},
+	{"KT128", NewKT128, nil, ptn(1), 32,
+		"FAB658DB63E94A246188BF7AF69A133045F46EE984C56E3C3328CAAF1AA1A583"},
+	{"KT128", NewKT128, ff(1), ptn(41), 32,
+		"D848C5068CED736F4462159B9867FD4C20B808ACC3D5BC48E0B06BA0A3762EC4"},
+	{"KT128", NewKT128, ff(3), ptn(41 * 41), 32,
+		"C389E5009AE57120854C2E8C64670AC01358CF4C1BAF89447A724234DC7CED74"},
+	{"KT128", NewKT128, ff(7), ptn(41 * 41 * 41), 32,
+		"75D2F86A2E644566726B4FBCFC5657B9DBCF070C7B0DCA06450AB291D7443BCF"},
+	{"KT128", NewKT128, ptn(8191), nil, 32,
+		"1B577636F723643E990CC7D6A659837436FD6A103626600EB8301CD1DBE553D6"},
+	{"KT128", NewKT128, ptn(8192), nil, 32,
+		"48F256F6772F9EDFB6A8B661EC92DC93B95EBD05A08A17B39AE3490870C926C3"},
+	{"KT128", NewKT128, ptn(8192), ptn(8189), 32,
+		"3ED12F70FB05DDB58689510AB3E4D23C6C6033849AA01E1D8C220A297FEDCD0B"},
+	{"KT128", NewKT128, ptn(8192), ptn(8190), 32,
+		"6A7C1B6A5CD0D8C9CA943A4A216CC64604559A2EA45F78570A15253D67BA00AE"},
+	{"KT256", NewKT256, nil, nil, 64,
+		"B23D2E9CEA9F4904E02BEC06817FC10CE38CE8E93EF4C89E6537076AF8646404" +
+			"E3E8B68107B8833A5D30490AA33482353FD4ADC7148ECB782855003AAEBDE4A9"},
+	{"KT256", NewKT256, nil, nil, 128,
+		"60F55760B61F82114C030C97E5178449608CCD2CD2D919FC7829FF69931AC4D0"},
+	{"KT256", NewKT256, nil, nil, 10064,
+		"56C64FE94958E7085F2964888259B9932752F3CCD855288EFEE5FCBB8B563069"},
+	{"KT256", NewKT256, ptn(1), nil, 64,
+		"0D005A194085360217128CF17F91E1F71314EFA5564539D444912E3437EFA17F" +
+			"82DB6F6FFE76E781EAA068BCE01F2BBF81EACB983D7230F2FB02834A21B1DDD0"},
+	{"KT256", NewKT256, ptn(17), nil, 64,
+		"1BA3C02B1FC514474F06C8979978A9056C8483F4A1B63D0DCCEFE3A28A2F323E" +
+			"1CDCCA40EBF006AC76EF0397152346837B1277D3E7FAA9C9653B19075098527B"},
+	{"KT256", NewKT256, ptn(17 * 17), nil, 64,
+		"DE8CCBC63E0F133EBB4416814D4C66F691BBF8B6A61EC0A7700F836B086CB029" +
+			"D54F12AC7159472C72DB118C35B4E6AA213C6562CAAA9DCC518959E69B10F3BA"},
+	{"KT256", NewKT256, ptn(17 * 17 * 17), nil, 64,
+		"647EFB49FE9D717500171B41E7F11BD491544443209997CE1C2530D15EB1FFBB" +
+			"598935EF954528FFC152B1E4D731EE2683680674365CD191D562BAE753B84AA5"},
+	{"KT256", NewKT256, ptn(17 * 17 * 17 * 17), nil, 64,
+		"B06275D284CD1CF205BCBE57DCCD3EC1FF6686E3ED15776383E1F2FA3C6AC8F0" +
+			"8BF8A162829DB1A44B2A43FF83DD89C3CF1CEB61EDE659766D5CCF817A62BA8D"},
+	{"KT256", NewKT256, ptn(17 * 17 * 17 * 17 * 17), nil, 64,
+		"9473831D76A4C7BF77ACE45B59F1458B1673D64BCD877A7C66B2664AA6DD149E" +
+			"60EAB71B5C2BAB858C074DED81DDCE2B4022B5215935C0D4D19BF511AEEB0772"},
+	{"KT256", NewKT256, nil, ptn(1), 64,
+		"9280F5CC39B54A5A594EC63DE0BB99371E4609D44BF845C2F5B8C316D72B1598" +
+			"11F748F23E3FABBE5C3226EC96C62186DF2D33E9DF74C5069CEECBB4DD10EFF6"},
+	{"KT256", NewKT256, ff(1), ptn(41), 64,
+		"47EF96DD616F200937AA7847E34EC2FEAE8087E3761DC0F8C1A154F51DC9CCF8" +
+			"45D7ADBCE57FF64B639722C6A1672E3BF5372D87E00AFF89BE97240756998853"},
+	{"KT256", NewKT256, ff(3), ptn(41 * 41), 64,
+		"3B48667A5051C5966C53C5D42B95DE451E05584E7806E2FB765EDA959074172C" +
+			"B438A9E91DDE337C98E9C41BED94C4E0AEF431D0B64EF2324F7932CAA6F54969"},
+	{"KT256", NewKT256, ff(7), ptn(41 * 41 * 41), 64,
+		"E0911CC00025E1540831E266D94ADD9B98712142B80D2629E643AAC4EFAF5A3A" +
+			"30A88CBF4AC2A91A2432743054FBCC9897670E86BA8CEC2FC2ACE9C966369724"},
+	{"KT256", NewKT256, ptn(8191), nil, 64,
+		"3081434D93A4108D8D8A3305B89682CEBEDC7CA4EA8A3CE869FBB73CBE4A58EE" +
+			"F6F24DE38FFC170514C70E7AB2D01F03812616E863D769AFB3753193BA045B20"},
+	{"KT256", NewKT256, ptn(8192), nil, 64,
+		"C6EE8E2AD3200C018AC87AAA031CDAC22121B412D07DC6E0DCCBB53423747E9A" +
+			"1C18834D99DF596CF0CF4B8DFAFB7BF02D139D0C9035725ADC1A01B7230A41FA"},
+	{"KT256", NewKT256, ptn(8192), ptn(8189), 64,
+		"74E47879F10A9C5D11BD2DA7E194FE57E86378BF3C3F7448EFF3C576A0F18C5C" +
+			"AAE0999979512090A7F348AF4260D4DE3C37F1ECAF8D2C2C96C1D16C64B12496"},
+	{"KT256", NewKT256, ptn(8192), ptn(8190), 64,
+		"F4B5908B929FFE01E0F79EC2F21243D41A396B2E7303A6AF1D6399CD6C7A0A2D" +
+			"D7C4F607E8277F9C9B1CB4AB9DDC59D4B92D1FC7558441F1832C3279A4241B8B"},
+}
+
+// TestKangarooTwelveVectors tests KT128 and KT256 against the RFC 9861
+// test vectors, writing the message at once and in uneven pieces.
+func TestKangarooTwelveVectors(t *testing.T) {
+	for _, v := range k12Vectors {
+		for _, writeSize := range []int{len(v.message) + 1, 7919, 1000} {
+			d := v.newK12(v.customization)
+			for msg := v.message; len(msg) > 0; {
+				n := writeSize
+				if n > len(msg) {
+					n = len(msg)
+				}
+				d.Write(msg[:n])
+				msg = msg[n:]
+			}
+			out := make([]byte, v.outputLen)
+			d.Read(out)
+			out = out[len(out)-len(v.digest)/2:]
+			if got := strings.ToUpper(hex.EncodeToString(out)); got != v.digest {
+				t.Errorf("%s(M=%d bytes, C=%d bytes, L=%d), writes of %d bytes:\ngot  %s\nwant %s",
+					v.name, len(v.message), len(v.customization), v.outputLen, writeSize, got, v.digest)
+			}
+		}
+	}
+}
+
+// TestKangarooTwelveCloneReset checks that Clone and Reset work while
+// leaves are being hashed.
+func TestKangarooTwelveCloneReset(t *testing.T) {
+	msg := ptn(5*k12ChunkSize + 17)
+	want := make([]byte, 32)
+	d := NewKT128([]byte("C"))
+	d.Write(msg)
+	d.Read(want)
+
+	d.Reset()
+	d.Write(msg[:3*k12ChunkSize+5])
+	d1 := d.Clone()
+	d.Write(msg[3*k12ChunkSize+5:])
+	d1.Write(msg[3*k12ChunkSize+5:])
+	for _, h := range []ShakeHash{d, d1} {
+		got := make([]byte, 32)
+		h.Read(got)
+		if !bytes.Equal(got, want) {
+			t.Errorf("got %x, want %x", got, want)
+		}
+	}
+}
+
+// TestKangarooTwelveCustomizationCopied checks that changing the
+// customization string after NewKT128 does not change the output.
+func TestKangarooTwelveCustomizationCopied(t *testing.T) {
+	want := make([]byte, 32)
+	d := NewKT128([]byte("C"))
+	d.Write([]byte(testString))
+	d.Read(want)
+
+	c := []byte("C")
+	d = NewKT128(c)
+	d.Write([]byte(testString))
+	c[0] = 'D'
+	got := make([]byte, 32)
+	d.Read(got)
+	if !bytes.Equal(got, want) {
+		t.Errorf("got %x, want %x", got, want)
+	}
+}
+
+func BenchmarkKT128_1MiB(b *testing.B) { benchmarkShake(b, NewKT128(nil), 1<<20) }
+func BenchmarkKT256_1MiB(b *testing.B) { benchmarkShake(b, NewKT256(nil), 1<<20) }
+
+// benchmarkShake measures the speed to hash a buffer of size bytes and
+// read 32 bytes of output.
+func benchmarkShake(b *testing.B, h ShakeHash, size int) {
+	data := sequentialBytes(size)
+	out := make([]byte, 32)
+	b.SetBytes(int64(size))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.Reset()
+		h.Write(data)
+		h.Read(out)
+	}
+}
@@ -35,8 +35,16 @@ var rc = [24]uint64{
 // keccakF1600 applies the Keccak permutation to a 1600b-wide
 // state represented as a slice of 25 uint64s.
 func keccakF1600(a *[25]uint64) {
+	keccakP1600(a, len(rc))
+}
+
+// keccakP1600Generic applies Keccak-p[1600, rounds], the Keccak
+// permutation reduced to its last rounds rounds, to a 1600b-wide state
+// represented as a slice of 25 uint64s. Keccak-p[1600, 24] is
+// Keccak-f[1600].
+func keccakP1600Generic(a *[25]uint64, rounds int) {
 	var t, bc0, bc1, bc2, bc3, bc4 uint64
-	for _, roundConstant := range rc {
+	for _, roundConstant := range rc[len(rc)-rounds:] {
 		// θ step
 		bc0 = a[0] ^ a[5] ^ a[10] ^ a[15] ^ a[20]
 		bc1 = a[1] ^ a[6] ^ a[11] ^ a[16] ^ a[21]
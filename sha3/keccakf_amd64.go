@@ -0,0 +1,86 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build amd64 && gc && !purego
+// +build amd64,gc,!purego
+
+package sha3
+
+// The assembly implementations in keccakf_amd64.s process two rounds per
+// iteration, alternating between the state and a copy on the stack, so
+// odd numbers of rounds are handled by the generic code.
+
+var (
+	// useBMI1 selects the implementation using ANDN instead of lane
+	// complementing.
+	useBMI1 bool
+
+	// useAVX2 selects the AVX2 implementation of the four-way
+	// permutation.
+	useAVX2 bool
+)
+
+func init() {
+	maxID, _, _, _ := cpuid(0, 0)
+	if maxID < 7 {
+		return
+	}
+	_, _, ecx1, _ := cpuid(1, 0)
+	_, ebx7, _, _ := cpuid(7, 0)
+	useBMI1 = ebx7&(1<<3) != 0
+
+	// AVX2 also needs the operating system to save the YMM registers.
+	osxsave := ecx1&(1<<27) != 0
+	avx := ecx1&(1<<28) != 0
+	if osxsave && avx {
+		xcr0, _ := xgetbv()
+		useAVX2 = xcr0&6 == 6 && ebx7&(1<<5) != 0
+	}
+}
+
+// cpuid executes the CPUID instruction with the given EAX and ECX inputs.
+func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+
+// xgetbv returns the contents of the XCR0 register.
+func xgetbv() (eax, edx uint32)
+
+// keccakP1600Lanes implements keccakP1600 with lane complementing, which
+// saves NOT instructions in the χ step. rounds must be even.
+//
+//go:noescape
+func keccakP1600Lanes(a *[25]uint64, rounds int)
+
+// keccakP1600BMI1 implements keccakP1600 with the BMI1 ANDN instruction.
+// rounds must be even.
+//
+//go:noescape
+func keccakP1600BMI1(a *[25]uint64, rounds int)
+
+// keccakP1600x4AVX2 implements keccakP1600x4 with AVX2 instructions.
+// rounds must be even.
+//
+//go:noescape
+func keccakP1600x4AVX2(a *[100]uint64, rounds int)
+
+// keccakP1600 applies Keccak-p[1600, rounds] to a.
+func keccakP1600(a *[25]uint64, rounds int) {
+	switch {
+	case rounds%2 != 0:
+		keccakP1600Generic(a, rounds)
+	case useBMI1:
+		keccakP1600BMI1(a, rounds)
+	default:
+		keccakP1600Lanes(a, rounds)
+	}
+}
+
+// keccakP1600x4 applies Keccak-p[1600, rounds] to each of the four
+// interleaved states in a.
+func keccakP1600x4(a *[100]uint64, rounds int) {
+	if useAVX2 && rounds%2 == 0 {
+		keccakP1600x4AVX2(a, rounds)
+	} else {
+		keccakP1600x4Generic(a, rounds)
+	}
+}
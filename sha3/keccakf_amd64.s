@@ -0,0 +1,542 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build amd64 && gc && !purego
+// +build amd64,gc,!purego
+
+// keccakP1600Lanes was translated into a form compatible with 6a from the
+// public domain sources at https://github.com/gvanas/KeccakCodePackage,
+// and loops over pairs of rounds so that the number of rounds can vary.
+// keccakP1600BMI1 computes each round from scratch, using ANDN for the χ
+// step instead of lane complementing.
+
+#include "textflag.h"
+
+// Offsets in state
+#define _ba  (0*8)
+#define _be  (1*8)
+#define _bi  (2*8)
+#define _bo  (3*8)
+#define _bu  (4*8)
+#define _ga  (5*8)
+#define _ge  (6*8)
+#define _gi  (7*8)
+#define _go  (8*8)
+#define _gu  (9*8)
+#define _ka (10*8)
+#define _ke (11*8)
+#define _ki (12*8)
+#define _ko (13*8)
+#define _ku (14*8)
+#define _ma (15*8)
+#define _me (16*8)
+#define _mi (17*8)
+#define _mo (18*8)
+#define _mu (19*8)
+#define _sa (20*8)
+#define _se (21*8)
+#define _si (22*8)
+#define _so (23*8)
+#define _su (24*8)
+
+// Temporary registers
+#define rT1  AX
+
+// Round vars
+#define rpState DI
+#define rpStack SP
+
+#define rDa BX
+#define rDe CX
+#define rDi DX
+#define rDo R8
+#define rDu R9
+
+#define rBa R10
+#define rBe R11
+#define rBi R12
+#define rBo R13
+#define rBu R14
+
+#define rCa SI
+#define rCe BP
+#define rCi rBi
+#define rCo rBo
+#define rCu R15
+
+#define MOVQ_RBI_RCE MOVQ rBi, rCe
+#define XORQ_RT1_RCA XORQ rT1, rCa
+#define XORQ_RT1_RCE XORQ rT1, rCe
+#define XORQ_RBA_RCU XORQ rBa, rCu
+#define XORQ_RBE_RCU XORQ rBe, rCu
+#define XORQ_RDU_RCU XORQ rDu, rCu
+#define XORQ_RDA_RCA XORQ rDa, rCa
+#define XORQ_RDE_RCE XORQ rDe, rCe
+
+#define mKeccakRound(iState, oState, rc, B_RBI_RCE, G_RT1_RCA, G_RT1_RCE, G_RBA_RCU, K_RT1_RCA, K_RT1_RCE, K_RBA_RCU, M_RT1_RCA, M_RT1_RCE, M_RBE_RCU, S_RDU_RCU, S_RDA_RCA, S_RDE_RCE) \
+	/* Prepare round */    \
+	MOVQ rCe, rDa;         \
+	ROLQ $1, rDa;          \
+	                       \
+	MOVQ _bi(iState), rCi; \
+	XORQ _gi(iState), rDi; \
+	XORQ rCu, rDa;         \
+	XORQ _ki(iState), rCi; \
+	XORQ _mi(iState), rDi; \
+	XORQ rDi, rCi;         \
+	                       \
+	MOVQ rCi, rDe;         \
+	ROLQ $1, rDe;          \
+	                       \
+	MOVQ _bo(iState), rCo; \
+	XORQ _go(iState), rDo; \
+	XORQ rCa, rDe;         \
+	XORQ _ko(iState), rCo; \
+	XORQ _mo(iState), rDo; \
+	XORQ rDo, rCo;         \
+	                       \
+	MOVQ rCo, rDi;         \
+	ROLQ $1, rDi;          \
+	                       \
+	MOVQ rCu, rDo;         \
+	XORQ rCe, rDi;         \
+	ROLQ $1, rDo;          \
+	                       \
+	MOVQ rCa, rDu;         \
+	XORQ rCi, rDo;         \
+	ROLQ $1, rDu;          \
+	                       \
+	/* Result b */         \
+	MOVQ _ba(iState), rBa; \
+	MOVQ _ge(iState), rBe; \
+	XORQ rCo, rDu;         \
+	MOVQ _ki(iState), rBi; \
+	MOVQ _mo(iState), rBo; \
+	MOVQ _su(iState), rBu; \
+	XORQ rDe, rBe;         \
+	ROLQ $44, rBe;         \
+	XORQ rDi, rBi;         \
+	XORQ rDa, rBa;         \
+	ROLQ $43, rBi;         \
+	                       \
+	MOVQ rBe, rCa;         \
+	MOVQ rc, rT1;          \
+	ORQ  rBi, rCa;         \
+	XORQ rBa, rT1;         \
+	XORQ rT1, rCa;         \
+	MOVQ rCa, _ba(oState); \
+	                       \
+	XORQ rDu, rBu;         \
+	ROLQ $14, rBu;         \
+	MOVQ rBa, rCu;         \
+	ANDQ rBe, rCu;         \
+	XORQ rBu, rCu;         \
+	MOVQ rCu, _bu(oState); \
+	                       \
+	XORQ rDo, rBo;         \
+	ROLQ $21, rBo;         \
+	MOVQ rBo, rT1;         \
+	ANDQ rBu, rT1;         \
+	XORQ rBi, rT1;         \
+	MOVQ rT1, _bi(oState); \
+	                       \
+	NOTQ rBi;              \
+	ORQ  rBa, rBu;         \
+	ORQ  rBo, rBi;         \
+	XORQ rBo, rBu;         \
+	XORQ rBe, rBi;         \
+	MOVQ rBu, _bo(oState); \
+	MOVQ rBi, _be(oState); \
+	B_RBI_RCE;             \
+	                       \
+	/* Result g */         \
+	MOVQ _gu(iState), rBe; \
+	XORQ rDu, rBe;         \
+	MOVQ _ka(iState), rBi; \
+	ROLQ $20, rBe;         \
+	XORQ rDa, rBi;         \
+	ROLQ $3, rBi;          \
+	MOVQ _bo(iState), rBa; \
+	MOVQ rBe, rT1;         \
+	ORQ  rBi, rT1;         \
+	XORQ rDo, rBa;         \
+	MOVQ _me(iState), rBo; \
+	MOVQ _si(iState), rBu; \
+	ROLQ $28, rBa;         \
+	XORQ rBa, rT1;         \
+	MOVQ rT1, _ga(oState); \
+	G_RT1_RCA;             \
+	                       \
+	XORQ rDe, rBo;         \
+	ROLQ $45, rBo;         \
+	MOVQ rBi, rT1;         \
+	ANDQ rBo, rT1;         \
+	XORQ rBe, rT1;         \
+	MOVQ rT1, _ge(oState); \
+	G_RT1_RCE;             \
+	                       \
+	XORQ rDi, rBu;         \
+	ROLQ $61, rBu;         \
+	MOVQ rBu, rT1;         \
+	ORQ  rBa, rT1;         \
+	XORQ rBo, rT1;         \
+	MOVQ rT1, _go(oState); \
+	                       \
+	ANDQ rBe, rBa;         \
+	XORQ rBu, rBa;         \
+	MOVQ rBa, _gu(oState); \
+	NOTQ rBu;              \
+	G_RBA_RCU;             \
+	                       \
+	ORQ  rBu, rBo;         \
+	XORQ rBi, rBo;         \
+	MOVQ rBo, _gi(oState); \
+	                       \
+	/* Result k */         \
+	MOVQ _be(iState), rBa; \
+	MOVQ _gi(iState), rBe; \
+	MOVQ _ko(iState), rBi; \
+	MOVQ _mu(iState), rBo; \
+	MOVQ _sa(iState), rBu; \
+	XORQ rDi, rBe;         \
+	ROLQ $6, rBe;          \
+	XORQ rDo, rBi;         \
+	ROLQ $25, rBi;         \
+	MOVQ rBe, rT1;         \
+	ORQ  rBi, rT1;         \
+	XORQ rDe, rBa;         \
+	ROLQ $1, rBa;          \
+	XORQ rBa, rT1;         \
+	MOVQ rT1, _ka(oState); \
+	K_RT1_RCA;             \
+	                       \
+	XORQ rDu, rBo;         \
+	ROLQ $8, rBo;          \
+	MOVQ rBi, rT1;         \
+	ANDQ rBo, rT1;         \
+	XORQ rBe, rT1;         \
+	MOVQ rT1, _ke(oState); \
+	K_RT1_RCE;             \
+	                       \
+	XORQ rDa, rBu;         \
+	ROLQ $18, rBu;         \
+	NOTQ rBo;              \
+	MOVQ rBo, rT1;         \
+	ANDQ rBu, rT1;         \
+	XORQ rBi, rT1;         \
+	MOVQ rT1, _ki(oState); \
+	                       \
+	MOVQ rBu, rT1;         \
+	ORQ  rBa, rT1;         \
+	XORQ rBo, rT1;         \
+	MOVQ rT1, _ko(oState); \
+	                       \
+	ANDQ rBe, rBa;         \
+	XORQ rBu, rBa;         \
+	MOVQ rBa, _ku(oState); \
+	K_RBA_RCU;             \
+	                       \
+	/* Result m */         \
+	MOVQ _ga(iState), rBe; \
+	XORQ rDa, rBe;         \
+	MOVQ _ke(iState), rBi; \
+	ROLQ $36, rBe;         \
+	XORQ rDe, rBi;         \
+	MOVQ _bu(iState), rBa; \
+	ROLQ $10, rBi;         \
+	MOVQ rBe, rT1;         \
+	MOVQ _mi(iState), rBo; \
+	ANDQ rBi, rT1;         \
+	XORQ rDu, rBa;         \
+	MOVQ _so(iState), rBu; \
+	ROLQ $27, rBa;         \
+	XORQ rBa, rT1;         \
+	MOVQ rT1, _ma(oState); \
+	M_RT1_RCA;             \
+	                       \
+	XORQ rDi, rBo;         \
+	ROLQ $15, rBo;         \
+	MOVQ rBi, rT1;         \
+	ORQ  rBo, rT1;         \
+	XORQ rBe, rT1;         \
+	MOVQ rT1, _me(oState); \
+	M_RT1_RCE;             \
+	                       \
+	XORQ rDo, rBu;         \
+	ROLQ $56, rBu;         \
+	NOTQ rBo;              \
+	MOVQ rBo, rT1;         \
+	ORQ  rBu, rT1;         \
+	XORQ rBi, rT1;         \
+	MOVQ rT1, _mi(oState); \
+	                       \
+	ORQ  rBa, rBe;         \
+	XORQ rBu, rBe;         \
+	MOVQ rBe, _mu(oState); \
+	                       \
+	ANDQ rBa, rBu;         \
+	XORQ rBo, rBu;         \
+	MOVQ rBu, _mo(oState); \
+	M_RBE_RCU;             \
+	                       \
+	/* Result s */         \
+	MOVQ _bi(iState), rBa; \
+	MOVQ _go(iState), rBe; \
+	MOVQ _ku(iState), rBi; \
+	XORQ rDi, rBa;         \
+	MOVQ _ma(iState), rBo; \
+	ROLQ $62, rBa;         \
+	XORQ rDo, rBe;         \
+	MOVQ _se(iState), rBu; \
+	ROLQ $55, rBe;         \
+	                       \
+	XORQ rDu, rBi;         \
+	MOVQ rBa, rDu;         \
+	XORQ rDe, rBu;         \
+	ROLQ $2, rBu;          \
+	ANDQ rBe, rDu;         \
+	XORQ rBu, rDu;         \
+	MOVQ rDu, _su(oState); \
+	                       \
+	ROLQ $39, rBi;         \
+	S_RDU_RCU;             \
+	NOTQ rBe;              \
+	XORQ rDa, rBo;         \
+	MOVQ rBe, rDa;         \
+	ANDQ rBi, rDa;         \
+	XORQ rBa, rDa;         \
+	MOVQ rDa, _sa(oState); \
+	S_RDA_RCA;             \
+	                       \
+	ROLQ $41, rBo;         \
+	MOVQ rBi, rDe;         \
+	ORQ  rBo, rDe;         \
+	XORQ rBe, rDe;         \
+	MOVQ rDe, _se(oState); \
+	S_RDE_RCE;             \
+	                       \
+	MOVQ rBo, rDi;         \
+	MOVQ rBu, rDo;         \
+	ANDQ rBu, rDi;         \
+	ORQ  rBa, rDo;         \
+	XORQ rBi, rDi;         \
+	XORQ rBo, rDo;         \
+	MOVQ rDi, _si(oState); \
+	MOVQ rDo, _so(oState)  \
+
+#define mKeccakRoundFull(iState, oState, rc) \
+	mKeccakRound(iState, oState, rc, MOVQ_RBI_RCE, XORQ_RT1_RCA, XORQ_RT1_RCE, XORQ_RBA_RCU, XORQ_RT1_RCA, XORQ_RT1_RCE, XORQ_RBA_RCU, XORQ_RT1_RCA, XORQ_RT1_RCE, XORQ_RBE_RCU, XORQ_RDU_RCU, XORQ_RDA_RCA, XORQ_RDE_RCE)
+
+// The frame holds the state between the two rounds of each iteration,
+// followed by a pointer to the next round constant and the number of
+// rounds left.
+#define _rcPtr 200
+#define _roundsLeft 208
+
+// func keccakP1600Lanes(a *[25]uint64, rounds int)
+TEXT ·keccakP1600Lanes(SB), 0, $216-16
+	MOVQ a+0(FP), rpState
+	MOVQ rounds+8(FP), AX
+	MOVQ AX, _roundsLeft(SP)
+	NEGQ AX
+	LEAQ ·rc(SB), BX
+	LEAQ (24*8)(BX)(AX*8), AX
+	MOVQ AX, _rcPtr(SP)
+
+	// Convert the user state into an internal state
+	NOTQ _be(rpState)
+	NOTQ _bi(rpState)
+	NOTQ _go(rpState)
+	NOTQ _ki(rpState)
+	NOTQ _mi(rpState)
+	NOTQ _sa(rpState)
+
+	// Execute the KeccakP permutation
+	MOVQ _ba(rpState), rCa
+	MOVQ _be(rpState), rCe
+	MOVQ _bu(rpState), rCu
+
+	XORQ _ga(rpState), rCa
+	XORQ _ge(rpState), rCe
+	XORQ _gu(rpState), rCu
+
+	XORQ _ka(rpState), rCa
+	XORQ _ke(rpState), rCe
+	XORQ _ku(rpState), rCu
+
+	XORQ _ma(rpState), rCa
+	XORQ _me(rpState), rCe
+	XORQ _mu(rpState), rCu
+
+	XORQ _sa(rpState), rCa
+	XORQ _se(rpState), rCe
+	MOVQ _si(rpState), rDi
+	MOVQ _so(rpState), rDo
+	XORQ _su(rpState), rCu
+
+lanesLoop:
+	MOVQ _rcPtr(SP), rT1
+	mKeccakRoundFull(rpState, rpStack, 0(rT1))
+	MOVQ _rcPtr(SP), rT1
+	mKeccakRoundFull(rpStack, rpState, 8(rT1))
+	ADDQ $16, _rcPtr(SP)
+	SUBQ $2, _roundsLeft(SP)
+	JNZ  lanesLoop
+
+	// Revert the internal state to the user state
+	NOTQ _be(rpState)
+	NOTQ _bi(rpState)
+	NOTQ _go(rpState)
+	NOTQ _ki(rpState)
+	NOTQ _mi(rpState)
+	NOTQ _sa(rpState)
+
+	RET
+
+// Registers of keccakP1600BMI1. The column parities are computed into the
+// registers later holding the five lanes of a plane.
+#define bA R10
+#define bE R11
+#define bI R12
+#define bO R13
+#define bU R14
+#define dA BX
+#define dE CX
+#define dI DX
+#define dO R8
+#define dU R9
+#define tT AX
+#define pRC SI
+#define nRounds R15
+
+// mTheta computes the θ effect of each column of iState.
+#define mTheta(iState) \
+	MOVQ _ba(iState), bA; \
+	MOVQ _be(iState), bE; \
+	MOVQ _bi(iState), bI; \
+	MOVQ _bo(iState), bO; \
+	MOVQ _bu(iState), bU; \
+	XORQ _ga(iState), bA; \
+	XORQ _ge(iState), bE; \
+	XORQ _gi(iState), bI; \
+	XORQ _go(iState), bO; \
+	XORQ _gu(iState), bU; \
+	XORQ _ka(iState), bA; \
+	XORQ _ke(iState), bE; \
+	XORQ _ki(iState), bI; \
+	XORQ _ko(iState), bO; \
+	XORQ _ku(iState), bU; \
+	XORQ _ma(iState), bA; \
+	XORQ _me(iState), bE; \
+	XORQ _mi(iState), bI; \
+	XORQ _mo(iState), bO; \
+	XORQ _mu(iState), bU; \
+	XORQ _sa(iState), bA; \
+	XORQ _se(iState), bE; \
+	XORQ _si(iState), bI; \
+	XORQ _so(iState), bO; \
+	XORQ _su(iState), bU; \
+	MOVQ bE, dA;          \
+	ROLQ $1, dA;          \
+	XORQ bU, dA;          \
+	MOVQ bI, dE;          \
+	ROLQ $1, dE;          \
+	XORQ bA, dE;          \
+	MOVQ bO, dI;          \
+	ROLQ $1, dI;          \
+	XORQ bE, dI;          \
+	MOVQ bU, dO;          \
+	ROLQ $1, dO;          \
+	XORQ bI, dO;          \
+	MOVQ bA, dU;          \
+	ROLQ $1, dU;          \
+	XORQ bO, dU
+
+// mLoad applies θ, ρ and π to the lane at offset off of iState.
+#define mLoad(iState, off, d, rot, b) \
+	MOVQ off(iState), b; \
+	XORQ d, b;           \
+	ROLQ $rot, b
+
+// mChi applies χ to one lane of a plane and stores it at offset off.
+#define mChi(b0, b1, b2, off, oState) \
+	ANDNQ b2, b1, tT;   \
+	XORQ  b0, tT;       \
+	MOVQ  tT, off(oState)
+
+// mPlane computes the five lanes of a plane but the first one of the
+// state, from the five lanes of iState that move into it.
+#define mPlane(iState, oState, l0, d0, r0, l1, d1, r1, l2, d2, r2, l3, d3, r3, l4, d4, r4, oa, oe, oi, oo, ou) \
+	mLoad(iState, l0, d0, r0, bA); \
+	mLoad(iState, l1, d1, r1, bE); \
+	mLoad(iState, l2, d2, r2, bI); \
+	mLoad(iState, l3, d3, r3, bO); \
+	mLoad(iState, l4, d4, r4, bU); \
+	mChi(bA, bE, bI, oa, oState);  \
+	mChi(bE, bI, bO, oe, oState);  \
+	mChi(bI, bO, bU, oi, oState);  \
+	mChi(bO, bU, bA, oo, oState);  \
+	mChi(bU, bA, bE, ou, oState)
+
+// mRoundBMI1 computes one round from iState into oState, with the round
+// constant at pRC.
+#define mRoundBMI1(iState, oState) \
+	mTheta(iState);                  \
+	                                 \
+	/* Plane b, which also gets ι */ \
+	MOVQ _ba(iState), bA;            \
+	XORQ dA, bA;                     \
+	mLoad(iState, _ge, dE, 44, bE);  \
+	mLoad(iState, _ki, dI, 43, bI);  \
+	mLoad(iState, _mo, dO, 21, bO);  \
+	mLoad(iState, _su, dU, 14, bU);  \
+	ANDNQ bI, bE, tT;                \
+	XORQ  bA, tT;                    \
+	XORQ  (pRC), tT;                 \
+	MOVQ  tT, _ba(oState);           \
+	mChi(bE, bI, bO, _be, oState);   \
+	mChi(bI, bO, bU, _bi, oState);   \
+	mChi(bO, bU, bA, _bo, oState);   \
+	mChi(bU, bA, bE, _bu, oState);   \
+	                                 \
+	mPlane(iState, oState, _bo, dO, 28, _gu, dU, 20, _ka, dA, 3, _me, dE, 45, _si, dI, 61, _ga, _ge, _gi, _go, _gu); \
+	mPlane(iState, oState, _be, dE, 1, _gi, dI, 6, _ko, dO, 25, _mu, dU, 8, _sa, dA, 18, _ka, _ke, _ki, _ko, _ku);  \
+	mPlane(iState, oState, _bu, dU, 27, _ga, dA, 36, _ke, dE, 10, _mi, dI, 15, _so, dO, 56, _ma, _me, _mi, _mo, _mu); \
+	mPlane(iState, oState, _bi, dI, 62, _go, dO, 55, _ku, dU, 39, _ma, dA, 41, _se, dE, 2, _sa, _se, _si, _so, _su); \
+	ADDQ $8, pRC
+
+// func keccakP1600BMI1(a *[25]uint64, rounds int)
+TEXT ·keccakP1600BMI1(SB), 0, $200-16
+	MOVQ a+0(FP), rpState
+	MOVQ rounds+8(FP), nRounds
+	MOVQ nRounds, tT
+	NEGQ tT
+	LEAQ ·rc(SB), pRC
+	LEAQ (24*8)(pRC)(tT*8), pRC
+
+bmi1Loop:
+	mRoundBMI1(rpState, rpStack)
+	mRoundBMI1(rpStack, rpState)
+	SUBQ $2, nRounds
+	JNZ  bmi1Loop
+	RET
+
+// func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+TEXT ·cpuid(SB), NOSPLIT, $0-24
+	MOVL eaxArg+0(FP), AX
+	MOVL ecxArg+4(FP), CX
+	CPUID
+	MOVL AX, eax+8(FP)
+	MOVL BX, ebx+12(FP)
+	MOVL CX, ecx+16(FP)
+	MOVL DX, edx+20(FP)
+	RET
+
+// func xgetbv() (eax, edx uint32)
+TEXT ·xgetbv(SB), NOSPLIT, $0-8
+	MOVL $0, CX
+	XGETBV
+	MOVL AX, eax+0(FP)
+	MOVL DX, edx+4(FP)
+	RET
@@ -0,0 +1,109 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build amd64 && gc && !purego
+// +build amd64,gc,!purego
+
+package sha3
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestKeccakP1600Assembly checks that the assembly implementations give
+// the same results as the generic code for all even numbers of rounds.
+func TestKeccakP1600Assembly(t *testing.T) {
+	impls := []struct {
+		name      string
+		available bool
+		permute   func(a *[25]uint64, rounds int)
+	}{
+		{"lane complementing", true, keccakP1600Lanes},
+		{"BMI1", useBMI1, keccakP1600BMI1},
+	}
+	r := rand.New(rand.NewSource(1))
+	for _, impl := range impls {
+		if !impl.available {
+			t.Logf("skipping %s implementation: not supported by the CPU", impl.name)
+			continue
+		}
+		for rounds := 2; rounds <= 24; rounds += 2 {
+			for i := 0; i < 10; i++ {
+				var a [25]uint64
+				for j := range a {
+					a[j] = r.Uint64()
+				}
+				want := a
+				keccakP1600Generic(&want, rounds)
+				impl.permute(&a, rounds)
+				if a != want {
+					t.Fatalf("%s, %d rounds: got %016x, want %016x", impl.name, rounds, a, want)
+				}
+			}
+		}
+	}
+}
+
+// TestKeccakP1600x4AVX2 checks that the AVX2 four-way permutation gives
+// the same results as the generic code for all even numbers of rounds.
+func TestKeccakP1600x4AVX2(t *testing.T) {
+	if !useAVX2 {
+		t.Skip("AVX2 not supported by the CPU")
+	}
+	r := rand.New(rand.NewSource(1))
+	for rounds := 2; rounds <= 24; rounds += 2 {
+		var a [100]uint64
+		for j := range a {
+			a[j] = r.Uint64()
+		}
+		want := a
+		keccakP1600x4Generic(&want, rounds)
+		keccakP1600x4AVX2(&a, rounds)
+		if a != want {
+			t.Fatalf("%d rounds: got %016x, want %016x", rounds, a, want)
+		}
+	}
+}
+
+func BenchmarkPermutationGeneric(b *testing.B) {
+	var a [25]uint64
+	for i := 0; i < b.N; i++ {
+		keccakP1600Generic(&a, 24)
+	}
+}
+
+func BenchmarkPermutationLanes(b *testing.B) {
+	var a [25]uint64
+	for i := 0; i < b.N; i++ {
+		keccakP1600Lanes(&a, 24)
+	}
+}
+
+func BenchmarkPermutationBMI1(b *testing.B) {
+	if !useBMI1 {
+		b.Skip("BMI1 not supported by the CPU")
+	}
+	var a [25]uint64
+	for i := 0; i < b.N; i++ {
+		keccakP1600BMI1(&a, 24)
+	}
+}
+
+func BenchmarkPermutationx4Generic(b *testing.B) {
+	var a [100]uint64
+	for i := 0; i < b.N; i++ {
+		keccakP1600x4Generic(&a, 24)
+	}
+}
+
+func BenchmarkPermutationx4AVX2(b *testing.B) {
+	if !useAVX2 {
+		b.Skip("AVX2 not supported by the CPU")
+	}
+	var a [100]uint64
+	for i := 0; i < b.N; i++ {
+		keccakP1600x4AVX2(&a, 24)
+	}
+}
@@ -0,0 +1,21 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build (!amd64 || !gc || purego) && !386 && !arm
+// +build !amd64 !gc purego
+// +build !386
+// +build !arm
+
+package sha3
+
+// keccakP1600 applies Keccak-p[1600, rounds] to a.
+func keccakP1600(a *[25]uint64, rounds int) {
+	keccakP1600Generic(a, rounds)
+}
+
+// keccakP1600x4 applies Keccak-p[1600, rounds] to each of the four
+// interleaved states in a.
+func keccakP1600x4(a *[100]uint64, rounds int) {
+	keccakP1600x4Generic(a, rounds)
+}
@@ -0,0 +1,247 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build 386 || arm
+// +build 386 arm
+
+package sha3
+
+// On 32-bit platforms, the lanes of the sponge state are stored
+// bit-interleaved, as described in section 2.1 of "Keccak implementation
+// overview" (http://keccak.noekeon.org/Keccak-implementation-3.2.pdf):
+// the even-numbered bits of a lane form its low 32-bit word and the
+// odd-numbered bits its high word. A 64-bit rotation then becomes two
+// 32-bit rotations, which 32-bit processors perform in one instruction
+// each, instead of a sequence of shifts and ORs across two registers. The
+// lanes are only converted when input is absorbed and output is squeezed.
+
+import (
+	"math/bits"
+)
+
+// unshuffle moves the even-numbered bits of x into its low half and its
+// odd-numbered bits into its high half, preserving their order.
+func unshuffle(x uint32) uint32 {
+	t := (x ^ (x >> 1)) & 0x22222222
+	x ^= t ^ (t << 1)
+	t = (x ^ (x >> 2)) & 0x0c0c0c0c
+	x ^= t ^ (t << 2)
+	t = (x ^ (x >> 4)) & 0x00f000f0
+	x ^= t ^ (t << 4)
+	t = (x ^ (x >> 8)) & 0x0000ff00
+	x ^= t ^ (t << 8)
+	return x
+}
+
+// shuffle is the inverse of unshuffle.
+func shuffle(x uint32) uint32 {
+	t := (x ^ (x >> 8)) & 0x0000ff00
+	x ^= t ^ (t << 8)
+	t = (x ^ (x >> 4)) & 0x00f000f0
+	x ^= t ^ (t << 4)
+	t = (x ^ (x >> 2)) & 0x0c0c0c0c
+	x ^= t ^ (t << 2)
+	t = (x ^ (x >> 1)) & 0x22222222
+	x ^= t ^ (t << 1)
+	return x
+}
+
+// loadLane converts a lane, read from a little-endian 64-bit word, into
+// its bit-interleaved form.
+func loadLane(x uint64) uint64 {
+	lo, hi := unshuffle(uint32(x)), unshuffle(uint32(x>>32))
+	even := lo&0x0000ffff | hi<<16
+	odd := lo>>16 | hi&0xffff0000
+	return uint64(odd)<<32 | uint64(even)
+}
+
+// storeLane converts a bit-interleaved lane back into the form defined in
+// FIPS 202.
+func storeLane(x uint64) uint64 {
+	even, odd := uint32(x), uint32(x>>32)
+	lo := shuffle(even&0x0000ffff | odd<<16)
+	hi := shuffle(even>>16 | odd&0xffff0000)
+	return uint64(hi)<<32 | uint64(lo)
+}
+
+// rcInterleaved holds the round constants in bit-interleaved form.
+var rcInterleaved = func() (t [24][2]uint32) {
+	for i, c := range rc {
+		x := loadLane(c)
+		t[i] = [2]uint32{uint32(x), uint32(x >> 32)}
+	}
+	return
+}()
+
+// keccakP1600Interleaved applies Keccak-p[1600, rounds] to a state of
+// bit-interleaved lanes.
+func keccakP1600Interleaved(a *[25]uint64, rounds int) {
+	var e, o [25]uint32 // the even and odd halves of the lanes
+	for i, lane := range a {
+		e[i], o[i] = uint32(lane), uint32(lane>>32)
+	}
+
+	var ce0, ce1, ce2, ce3, ce4, co0, co1, co2, co3, co4 uint32
+	var de, do uint32
+	var be, bo [25]uint32
+	for _, c := range rcInterleaved[len(rc)-rounds:] {
+		// θ step: the parity of each column.
+		ce0 = e[0] ^ e[5] ^ e[10] ^ e[15] ^ e[20]
+		ce1 = e[1] ^ e[6] ^ e[11] ^ e[16] ^ e[21]
+		ce2 = e[2] ^ e[7] ^ e[12] ^ e[17] ^ e[22]
+		ce3 = e[3] ^ e[8] ^ e[13] ^ e[18] ^ e[23]
+		ce4 = e[4] ^ e[9] ^ e[14] ^ e[19] ^ e[24]
+		co0 = o[0] ^ o[5] ^ o[10] ^ o[15] ^ o[20]
+		co1 = o[1] ^ o[6] ^ o[11] ^ o[16] ^ o[21]
+		co2 = o[2] ^ o[7] ^ o[12] ^ o[17] ^ o[22]
+		co3 = o[3] ^ o[8] ^ o[13] ^ o[18] ^ o[23]
+		co4 = o[4] ^ o[9] ^ o[14] ^ o[19] ^ o[24]
+
+		// θ, ρ and π steps, a column at a time. D[x] is
+		// C[x-1] ^ (C[x+1] <<< 1).
+		de = ce4 ^ bits.RotateLeft32(co1, 1)
+		do = co4 ^ ce1
+		be[0] = e[0] ^ de
+		bo[0] = o[0] ^ do
+		be[16] = bits.RotateLeft32(e[5]^de, 18)
+		bo[16] = bits.RotateLeft32(o[5]^do, 18)
+		be[7] = bits.RotateLeft32(o[10]^do, 2)
+		bo[7] = bits.RotateLeft32(e[10]^de, 1)
+		be[23] = bits.RotateLeft32(o[15]^do, 21)
+		bo[23] = bits.RotateLeft32(e[15]^de, 20)
+		be[14] = bits.RotateLeft32(e[20]^de, 9)
+		bo[14] = bits.RotateLeft32(o[20]^do, 9)
+		de = ce0 ^ bits.RotateLeft32(co2, 1)
+		do = co0 ^ ce2
+		be[10] = bits.RotateLeft32(o[1]^do, 1)
+		bo[10] = e[1] ^ de
+		be[1] = bits.RotateLeft32(e[6]^de, 22)
+		bo[1] = bits.RotateLeft32(o[6]^do, 22)
+		be[17] = bits.RotateLeft32(e[11]^de, 5)
+		bo[17] = bits.RotateLeft32(o[11]^do, 5)
+		be[8] = bits.RotateLeft32(o[16]^do, 23)
+		bo[8] = bits.RotateLeft32(e[16]^de, 22)
+		be[24] = bits.RotateLeft32(e[21]^de, 1)
+		bo[24] = bits.RotateLeft32(o[21]^do, 1)
+		de = ce1 ^ bits.RotateLeft32(co3, 1)
+		do = co1 ^ ce3
+		be[20] = bits.RotateLeft32(e[2]^de, 31)
+		bo[20] = bits.RotateLeft32(o[2]^do, 31)
+		be[11] = bits.RotateLeft32(e[7]^de, 3)
+		bo[11] = bits.RotateLeft32(o[7]^do, 3)
+		be[2] = bits.RotateLeft32(o[12]^do, 22)
+		bo[2] = bits.RotateLeft32(e[12]^de, 21)
+		be[18] = bits.RotateLeft32(o[17]^do, 8)
+		bo[18] = bits.RotateLeft32(e[17]^de, 7)
+		be[9] = bits.RotateLeft32(o[22]^do, 31)
+		bo[9] = bits.RotateLeft32(e[22]^de, 30)
+		de = ce2 ^ bits.RotateLeft32(co4, 1)
+		do = co2 ^ ce4
+		be[5] = bits.RotateLeft32(e[3]^de, 14)
+		bo[5] = bits.RotateLeft32(o[3]^do, 14)
+		be[21] = bits.RotateLeft32(o[8]^do, 28)
+		bo[21] = bits.RotateLeft32(e[8]^de, 27)
+		be[12] = bits.RotateLeft32(o[13]^do, 13)
+		bo[12] = bits.RotateLeft32(e[13]^de, 12)
+		be[3] = bits.RotateLeft32(o[18]^do, 11)
+		bo[3] = bits.RotateLeft32(e[18]^de, 10)
+		be[19] = bits.RotateLeft32(e[23]^de, 28)
+		bo[19] = bits.RotateLeft32(o[23]^do, 28)
+		de = ce3 ^ bits.RotateLeft32(co0, 1)
+		do = co3 ^ ce0
+		be[15] = bits.RotateLeft32(o[4]^do, 14)
+		bo[15] = bits.RotateLeft32(e[4]^de, 13)
+		be[6] = bits.RotateLeft32(e[9]^de, 10)
+		bo[6] = bits.RotateLeft32(o[9]^do, 10)
+		be[22] = bits.RotateLeft32(o[14]^do, 20)
+		bo[22] = bits.RotateLeft32(e[14]^de, 19)
+		be[13] = bits.RotateLeft32(e[19]^de, 4)
+		bo[13] = bits.RotateLeft32(o[19]^do, 4)
+		be[4] = bits.RotateLeft32(e[24]^de, 7)
+		bo[4] = bits.RotateLeft32(o[24]^do, 7)
+
+		// χ step
+		e[0] = be[0] ^ (^be[1] & be[2])
+		e[1] = be[1] ^ (^be[2] & be[3])
+		e[2] = be[2] ^ (^be[3] & be[4])
+		e[3] = be[3] ^ (^be[4] & be[0])
+		e[4] = be[4] ^ (^be[0] & be[1])
+		o[0] = bo[0] ^ (^bo[1] & bo[2])
+		o[1] = bo[1] ^ (^bo[2] & bo[3])
+		o[2] = bo[2] ^ (^bo[3] & bo[4])
+		o[3] = bo[3] ^ (^bo[4] & bo[0])
+		o[4] = bo[4] ^ (^bo[0] & bo[1])
+		e[5] = be[5] ^ (^be[6] & be[7])
+		e[6] = be[6] ^ (^be[7] & be[8])
+		e[7] = be[7] ^ (^be[8] & be[9])
+		e[8] = be[8] ^ (^be[9] & be[5])
+		e[9] = be[9] ^ (^be[5] & be[6])
+		o[5] = bo[5] ^ (^bo[6] & bo[7])
+		o[6] = bo[6] ^ (^bo[7] & bo[8])
+		o[7] = bo[7] ^ (^bo[8] & bo[9])
+		o[8] = bo[8] ^ (^bo[9] & bo[5])
+		o[9] = bo[9] ^ (^bo[5] & bo[6])
+		e[10] = be[10] ^ (^be[11] & be[12])
+		e[11] = be[11] ^ (^be[12] & be[13])
+		e[12] = be[12] ^ (^be[13] & be[14])
+		e[13] = be[13] ^ (^be[14] & be[10])
+		e[14] = be[14] ^ (^be[10] & be[11])
+		o[10] = bo[10] ^ (^bo[11] & bo[12])
+		o[11] = bo[11] ^ (^bo[12] & bo[13])
+		o[12] = bo[12] ^ (^bo[13] & bo[14])
+		o[13] = bo[13] ^ (^bo[14] & bo[10])
+		o[14] = bo[14] ^ (^bo[10] & bo[11])
+		e[15] = be[15] ^ (^be[16] & be[17])
+		e[16] = be[16] ^ (^be[17] & be[18])
+		e[17] = be[17] ^ (^be[18] & be[19])
+		e[18] = be[18] ^ (^be[19] & be[15])
+		e[19] = be[19] ^ (^be[15] & be[16])
+		o[15] = bo[15] ^ (^bo[16] & bo[17])
+		o[16] = bo[16] ^ (^bo[17] & bo[18])
+		o[17] = bo[17] ^ (^bo[18] & bo[19])
+		o[18] = bo[18] ^ (^bo[19] & bo[15])
+		o[19] = bo[19] ^ (^bo[15] & bo[16])
+		e[20] = be[20] ^ (^be[21] & be[22])
+		e[21] = be[21] ^ (^be[22] & be[23])
+		e[22] = be[22] ^ (^be[23] & be[24])
+		e[23] = be[23] ^ (^be[24] & be[20])
+		e[24] = be[24] ^ (^be[20] & be[21])
+		o[20] = bo[20] ^ (^bo[21] & bo[22])
+		o[21] = bo[21] ^ (^bo[22] & bo[23])
+		o[22] = bo[22] ^ (^bo[23] & bo[24])
+		o[23] = bo[23] ^ (^bo[24] & bo[20])
+		o[24] = bo[24] ^ (^bo[20] & bo[21])
+
+		// ι step
+		e[0] ^= c[0]
+		o[0] ^= c[1]
+	}
+
+	for i := range a {
+		a[i] = uint64(o[i])<<32 | uint64(e[i])
+	}
+}
+
+// permuteLanes applies Keccak-p[1600, rounds] to a sponge state.
+func permuteLanes(a *[25]uint64, rounds int) {
+	keccakP1600Interleaved(a, rounds)
+}
+
+// keccakP1600 applies Keccak-p[1600, rounds] to a, whose lanes are not
+// interleaved.
+func keccakP1600(a *[25]uint64, rounds int) {
+	for i := range a {
+		a[i] = loadLane(a[i])
+	}
+	keccakP1600Interleaved(a, rounds)
+	for i := range a {
+		a[i] = storeLane(a[i])
+	}
+}
+
+// keccakP1600x4 applies Keccak-p[1600, rounds] to each of the four
+// interleaved states in a.
+func keccakP1600x4(a *[100]uint64, rounds int) {
+	keccakP1600x4Generic(a, rounds)
+}
@@ -0,0 +1,65 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build 386 || arm
+// +build 386 arm
+
+package sha3
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestInterleaveLane checks loadLane against a bit-by-bit interleaving,
+// and that storeLane inverts it.
+func TestInterleaveLane(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		x := uint64(r.Int63())<<1 ^ uint64(r.Int63())
+		var want uint64
+		for b := uint(0); b < 32; b++ {
+			want |= (x >> (2 * b) & 1) << b
+			want |= (x >> (2*b + 1) & 1) << (32 + b)
+		}
+		if got := loadLane(x); got != want {
+			t.Fatalf("loadLane(%016x) = %016x, want %016x", x, got, want)
+		}
+		if got := storeLane(want); got != x {
+			t.Fatalf("storeLane(%016x) = %016x, want %016x", want, got, x)
+		}
+	}
+}
+
+// TestKeccakP1600Interleaved cross-checks the bit-interleaved permutation
+// with the 64-bit code for all numbers of rounds.
+func TestKeccakP1600Interleaved(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for rounds := 1; rounds <= 24; rounds++ {
+		var a [25]uint64
+		for i := range a {
+			a[i] = uint64(r.Int63())<<1 ^ uint64(r.Int63())
+		}
+		want := a
+		keccakP1600Generic(&want, rounds)
+		keccakP1600(&a, rounds)
+		if a != want {
+			t.Fatalf("%d rounds: got %016x, want %016x", rounds, a, want)
+		}
+	}
+}
+
+func BenchmarkPermutationGeneric(b *testing.B) {
+	var a [25]uint64
+	for i := 0; i < b.N; i++ {
+		keccakP1600Generic(&a, 24)
+	}
+}
+
+func BenchmarkPermutationInterleaved(b *testing.B) {
+	var a [25]uint64
+	for i := 0; i < b.N; i++ {
+		keccakP1600Interleaved(&a, 24)
+	}
+}
@@ -0,0 +1,26 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+// This file provides the four-way Keccak-p[1600, n] permutation used by
+// the batch hashing functions. Four independent states are interleaved
+// lane by lane, so that lane i of state j is a[4*i+j]: the four copies of
+// each lane are contiguous and can be processed together by vector
+// instructions.
+
+// keccakP1600x4Generic permutes the four states of a one after the other
+// with the scalar permutation.
+func keccakP1600x4Generic(a *[100]uint64, rounds int) {
+	var s [25]uint64
+	for j := 0; j < 4; j++ {
+		for i := range s {
+			s[i] = a[4*i+j]
+		}
+		keccakP1600(&s, rounds)
+		for i := range s {
+			a[4*i+j] = s[i]
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build amd64 && gc && !purego
+// +build amd64,gc,!purego
+
+// keccakP1600x4AVX2 holds the four copies of a lane, which are
+// interleaved in memory, in one YMM register. Each round is computed from
+// scratch, alternating between the state and a copy on the stack.
+
+#include "textflag.h"
+
+// Registers. The column parities are computed into the registers later
+// holding the five lanes of a plane.
+#define bA Y0
+#define bE Y1
+#define bI Y2
+#define bO Y3
+#define bU Y4
+#define dA Y5
+#define dE Y6
+#define dI Y7
+#define dO Y8
+#define dU Y9
+#define tT Y10
+#define tR Y11
+#define tRC Y12
+#define pRC SI
+#define nRounds CX
+
+// vRol rotates each 64-bit element of r left by n bits.
+#define vRol(r, n) \
+	VPSLLQ $(n), r, tR;    \
+	VPSRLQ $(64-(n)), r, r; \
+	VPOR   tR, r, r
+
+// vTheta computes the θ effect of each column of iState.
+#define vTheta(iState) \
+	VMOVDQU (0*32)(iState), bA;      \
+	VMOVDQU (1*32)(iState), bE;      \
+	VMOVDQU (2*32)(iState), bI;      \
+	VMOVDQU (3*32)(iState), bO;      \
+	VMOVDQU (4*32)(iState), bU;      \
+	VPXOR   (5*32)(iState), bA, bA;  \
+	VPXOR   (6*32)(iState), bE, bE;  \
+	VPXOR   (7*32)(iState), bI, bI;  \
+	VPXOR   (8*32)(iState), bO, bO;  \
+	VPXOR   (9*32)(iState), bU, bU;  \
+	VPXOR   (10*32)(iState), bA, bA; \
+	VPXOR   (11*32)(iState), bE, bE; \
+	VPXOR   (12*32)(iState), bI, bI; \
+	VPXOR   (13*32)(iState), bO, bO; \
+	VPXOR   (14*32)(iState), bU, bU; \
+	VPXOR   (15*32)(iState), bA, bA; \
+	VPXOR   (16*32)(iState), bE, bE; \
+	VPXOR   (17*32)(iState), bI, bI; \
+	VPXOR   (18*32)(iState), bO, bO; \
+	VPXOR   (19*32)(iState), bU, bU; \
+	VPXOR   (20*32)(iState), bA, bA; \
+	VPXOR   (21*32)(iState), bE, bE; \
+	VPXOR   (22*32)(iState), bI, bI; \
+	VPXOR   (23*32)(iState), bO, bO; \
+	VPXOR   (24*32)(iState), bU, bU; \
+	VMOVDQA bE, dA;                  \
+	vRol(dA, 1);                     \
+	VPXOR   bU, dA, dA;              \
+	VMOVDQA bI, dE;                  \
+	vRol(dE, 1);                     \
+	VPXOR   bA, dE, dE;              \
+	VMOVDQA bO, dI;                  \
+	vRol(dI, 1);                     \
+	VPXOR   bE, dI, dI;              \
+	VMOVDQA bU, dO;                  \
+	vRol(dO, 1);                     \
+	VPXOR   bI, dO, dO;              \
+	VMOVDQA bA, dU;                  \
+	vRol(dU, 1);                     \
+	VPXOR   bO, dU, dU
+
+// vLoad applies θ, ρ and π to lane l of iState.
+#define vLoad(iState, l, d, rot, b) \
+	VMOVDQU (l*32)(iState), b; \
+	VPXOR   d, b, b;           \
+	vRol(b, rot)
+
+// vChi applies χ to one lane of a plane and stores it as lane l.
+#define vChi(b0, b1, b2, l, oState) \
+	VPANDN  b2, b1, tT; \
+	VPXOR   b0, tT, tT; \
+	VMOVDQU tT, (l*32)(oState)
+
+// vPlane computes the five lanes of a plane but the first one of the
+// state, from the five lanes of iState that move into it.
+#define vPlane(iState, oState, l0, d0, r0, l1, d1, r1, l2, d2, r2, l3, d3, r3, l4, d4, r4, o) \
+	vLoad(iState, l0, d0, r0, bA);   \
+	vLoad(iState, l1, d1, r1, bE);   \
+	vLoad(iState, l2, d2, r2, bI);   \
+	vLoad(iState, l3, d3, r3, bO);   \
+	vLoad(iState, l4, d4, r4, bU);   \
+	vChi(bA, bE, bI, (o+0), oState); \
+	vChi(bE, bI, bO, (o+1), oState); \
+	vChi(bI, bO, bU, (o+2), oState); \
+	vChi(bO, bU, bA, (o+3), oState); \
+	vChi(bU, bA, bE, (o+4), oState)
+
+// vRound computes one round from iState into oState, with the round
+// constant at pRC.
+#define vRound(iState, oState) \
+	vTheta(iState);                  \
+	                                 \
+	/* Plane 0, which also gets ι */ \
+	VMOVDQU (0*32)(iState), bA;      \
+	VPXOR   dA, bA, bA;              \
+	vLoad(iState, 6, dE, 44, bE);    \
+	vLoad(iState, 12, dI, 43, bI);   \
+	vLoad(iState, 18, dO, 21, bO);   \
+	vLoad(iState, 24, dU, 14, bU);   \
+	VPBROADCASTQ (pRC), tRC;         \
+	VPANDN  bI, bE, tT;              \
+	VPXOR   bA, tT, tT;              \
+	VPXOR   tRC, tT, tT;             \
+	VMOVDQU tT, (0*32)(oState);      \
+	vChi(bE, bI, bO, 1, oState);     \
+	vChi(bI, bO, bU, 2, oState);     \
+	vChi(bO, bU, bA, 3, oState);     \
+	vChi(bU, bA, bE, 4, oState);     \
+	                                 \
+	vPlane(iState, oState, 3, dO, 28, 9, dU, 20, 10, dA, 3, 16, dE, 45, 22, dI, 61, 5);  \
+	vPlane(iState, oState, 1, dE, 1, 7, dI, 6, 13, dO, 25, 19, dU, 8, 20, dA, 18, 10);   \
+	vPlane(iState, oState, 4, dU, 27, 5, dA, 36, 11, dE, 10, 17, dI, 15, 23, dO, 56, 15); \
+	vPlane(iState, oState, 2, dI, 62, 8, dO, 55, 14, dU, 39, 15, dA, 41, 21, dE, 2, 20);  \
+	ADDQ $8, pRC
+
+// func keccakP1600x4AVX2(a *[100]uint64, rounds int)
+TEXT ·keccakP1600x4AVX2(SB), 0, $800-16
+	MOVQ a+0(FP), DI
+	MOVQ rounds+8(FP), nRounds
+	MOVQ nRounds, AX
+	NEGQ AX
+	LEAQ ·rc(SB), pRC
+	LEAQ (24*8)(pRC)(AX*8), pRC
+
+loop:
+	vRound(DI, SP)
+	vRound(SP, DI)
+	SUBQ $2, nRounds
+	JNZ  loop
+
+	VZEROUPPER
+	RET
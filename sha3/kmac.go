@@ -0,0 +1,161 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+// This file provides functions for creating instances of KMAC, the
+// Keccak message authentication code defined in NIST SP 800-185, in
+// both its fixed-output-length and XOF variants.
+
+import (
+	"encoding/binary"
+	"hash"
+)
+
+// kmac is a KMAC128 or KMAC256 instance. The underlying cSHAKE sponge has
+// already absorbed the padded key when the instance is created. It has no
+// Read method, so that a fixed-output-length KMAC can not be mistaken for
+// a KMACXOF.
+type kmac struct {
+	c *cshakeState
+
+	// keyBlock is bytepad(encode_string(K), rate), which is absorbed
+	// again by Reset.
+	keyBlock []byte
+
+	// outputLen is the output length in bytes, which is absorbed before
+	// padding. It is zero for the XOF variants.
+	outputLen int
+}
+
+// rightEncode returns the encoding of x as defined by right_encode in
+// section 2.3.1 of SP 800-185: the big-endian bytes of x, with leading
+// zeros removed, followed by their count.
+func rightEncode(x uint64) []byte {
+	var b [9]byte
+	binary.BigEndian.PutUint64(b[:8], x)
+	// Strip all but the last leading zero byte.
+	i := 0
+	for i < 7 && b[i] == 0 {
+		i++
+	}
+	b[8] = byte(8 - i)
+	return b[i:]
+}
+
+func newKMAC(key, S []byte, rate, outputLen int) *kmac {
+	encodedKey := encodeString(key)
+	k := &kmac{
+		c:         newCShake([]byte("KMAC"), S, rate),
+		keyBlock:  bytepad(encodedKey, rate),
+		outputLen: outputLen,
+	}
+	for i := range encodedKey {
+		encodedKey[i] = 0
+	}
+	k.c.Write(k.keyBlock)
+	return k
+}
+
+// BlockSize returns the rate of the sponge underlying KMAC.
+func (k *kmac) BlockSize() int { return k.c.rate }
+
+// Size returns the output size of KMAC in bytes.
+func (k *kmac) Size() int { return k.outputLen }
+
+// Write absorbs more data into the MAC's state. It panics if input is
+// written to it after output has been read from it.
+func (k *kmac) Write(p []byte) (int, error) { return k.c.Write(p) }
+
+// Reset resets the KMAC instance to its keyed initial state.
+func (k *kmac) Reset() {
+	k.c.Reset()
+	k.c.Write(k.keyBlock)
+}
+
+// Sum appends the output length and squeezes Size bytes of output from a
+// copy of the state, so that the caller can keep writing and summing.
+func (k *kmac) Sum(in []byte) []byte {
+	dup := k.c.clone()
+	dup.Write(rightEncode(uint64(k.outputLen) * 8))
+	mac := make([]byte, k.outputLen)
+	dup.Read(mac)
+	dup.Wipe()
+	return append(in, mac...)
+}
+
+// kmacXOF is a KMACXOF128 or KMACXOF256 instance: a kmac with an output
+// length of zero, whose output is read as from a ShakeHash.
+type kmacXOF struct {
+	kmac
+}
+
+// Read squeezes an arbitrary number of bytes of KMACXOF output.
+func (k *kmacXOF) Read(out []byte) (int, error) {
+	if k.c.state.state == spongeAbsorbing {
+		// An arbitrary output length is encoded as zero.
+		k.c.Write(rightEncode(0))
+	}
+	return k.c.Read(out)
+}
+
+// Clone returns a copy of the KMACXOF instance in its current state.
+func (k *kmacXOF) Clone() ShakeHash {
+	return &kmacXOF{kmac{
+		c:        k.c.Clone().(*cshakeState),
+		keyBlock: append([]byte(nil), k.keyBlock...),
+	}}
+}
+
+// NewKMAC128 creates a new KMAC128 instance keyed with key, which produces
+// outputLen bytes of output. customization is an optional customization
+// string that separates this use of the key from any other. KMAC128 has a
+// security strength of 128 bits if the key is at least 16 bytes long.
+//
+// The instance keeps a padded copy of the key for Reset for as long as it
+// is in use. Call its Wipe method, through the Wiper interface, to clear
+// it when done.
+func NewKMAC128(key []byte, outputLen int, customization []byte) hash.Hash {
+	if outputLen <= 0 {
+		panic("sha3: non-positive KMAC output length")
+	}
+	return newKMAC(key, customization, rate128, outputLen)
+}
+
+// NewKMAC256 creates a new KMAC256 instance keyed with key, which produces
+// outputLen bytes of output. customization is an optional customization
+// string that separates this use of the key from any other. KMAC256 has a
+// security strength of 256 bits if the key is at least 32 bytes long.
+//
+// The instance keeps a padded copy of the key for Reset for as long as it
+// is in use. Call its Wipe method, through the Wiper interface, to clear
+// it when done.
+func NewKMAC256(key []byte, outputLen int, customization []byte) hash.Hash {
+	if outputLen <= 0 {
+		panic("sha3: non-positive KMAC output length")
+	}
+	return newKMAC(key, customization, rate256, outputLen)
+}
+
+// NewKMACXOF128 creates a new KMACXOF128 instance keyed with key, which
+// produces an arbitrary amount of output through its Read method.
+// KMACXOF128 output does not depend on how much of it is read.
+//
+// The instance keeps a padded copy of the key for Reset for as long as it
+// is in use. Call its Wipe method, through the Wiper interface, to clear
+// it when done.
+func NewKMACXOF128(key, customization []byte) ShakeHash {
+	return &kmacXOF{*newKMAC(key, customization, rate128, 0)}
+}
+
+// NewKMACXOF256 creates a new KMACXOF256 instance keyed with key, which
+// produces an arbitrary amount of output through its Read method.
+// KMACXOF256 output does not depend on how much of it is read.
+//
+// The instance keeps a padded copy of the key for Reset for as long as it
+// is in use. Call its Wipe method, through the Wiper interface, to clear
+// it when done.
+func NewKMACXOF256(key, customization []byte) ShakeHash {
+	return &kmacXOF{*newKMAC(key, customization, rate256, 0)}
+}
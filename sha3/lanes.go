@@ -0,0 +1,24 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !386 && !arm
+// +build !386,!arm
+
+package sha3
+
+// On 64-bit platforms, the lanes of the sponge state are stored as they
+// are defined in FIPS 202.
+
+// loadLane converts a lane, read from a little-endian 64-bit word, into
+// the form in which the sponge state is stored.
+func loadLane(x uint64) uint64 { return x }
+
+// storeLane converts a lane of the sponge state back into the form
+// defined in FIPS 202.
+func storeLane(x uint64) uint64 { return x }
+
+// permuteLanes applies Keccak-p[1600, rounds] to a sponge state.
+func permuteLanes(a *[25]uint64, rounds int) {
+	keccakP1600(a, rounds)
+}
@@ -0,0 +1,47 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+// This file provides functions for creating instances of the legacy
+// Keccak-256 and Keccak-512 hash functions, as originally submitted to the
+// SHA-3 competition. They differ from SHA3-256 and SHA3-512 only in their
+// padding: the original submission has no domain-separation bits, so its
+// dsbyte holds just the first bit of the padding.
+//
+// These functions are not FIPS-202 hash functions. They are provided for
+// interoperability with existing systems, such as Ethereum, that adopted
+// Keccak before it was standardized. New systems should use SHA-3.
+
+import (
+	"hash"
+)
+
+// dsbyteKeccak is the domain separation value of the legacy Keccak hash
+// functions: no domain bits, followed by the first one bit of padding.
+const dsbyteKeccak = 0x01
+
+// NewLegacyKeccak256 creates a new Keccak-256 hash, using the padding of
+// the original Keccak submission rather than that of FIPS-202.
+func NewLegacyKeccak256() hash.Hash { return &state{rate: 136, outputLen: 32, dsbyte: dsbyteKeccak} }
+
+// NewLegacyKeccak512 creates a new Keccak-512 hash, using the padding of
+// the original Keccak submission rather than that of FIPS-202.
+func NewLegacyKeccak512() hash.Hash { return &state{rate: 72, outputLen: 64, dsbyte: dsbyteKeccak} }
+
+// LegacyKeccakSum256 returns the legacy Keccak-256 digest of the data.
+func LegacyKeccakSum256(data []byte) (digest [32]byte) {
+	h := NewLegacyKeccak256()
+	h.Write(data)
+	h.Sum(digest[:0])
+	return
+}
+
+// LegacyKeccakSum512 returns the legacy Keccak-512 digest of the data.
+func LegacyKeccakSum512(data []byte) (digest [64]byte) {
+	h := NewLegacyKeccak512()
+	h.Write(data)
+	h.Sum(digest[:0])
+	return
+}
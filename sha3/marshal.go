@@ -0,0 +1,162 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+// This file implements encoding.BinaryMarshaler and
+// encoding.BinaryUnmarshaler for the SHA-3 and SHAKE states, so that a
+// long computation can be saved and resumed later.
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	// marshalMagic starts every marshaled state.
+	marshalMagic = "sha3"
+
+	// marshalVersion is the version of the format of marshaled states.
+	marshalVersion = 1
+
+	// marshaledHeaderSize is the size of a marshaled state without its
+	// buffered input: magic || version || rate || dsbyte || rounds ||
+	// outputLen (32 bits, big-endian) || direction || buffer position ||
+	// lanes.
+	marshaledHeaderSize = len(marshalMagic) + 10 + 200
+)
+
+var (
+	errMarshalInvalid  = errors.New("sha3: invalid hash state")
+	errMarshalVersion  = errors.New("sha3: unsupported hash state version")
+	errMarshalMismatch = errors.New("sha3: hash state is for a different function")
+	errMarshalBits     = errors.New("sha3: cannot marshal hash state with a partial byte of input")
+)
+
+// MarshalBinary returns the state of the hash, which can be restored with
+// UnmarshalBinary into an instance of the same function.
+func (d *state) MarshalBinary() ([]byte, error) {
+	return d.AppendBinary(make([]byte, 0, marshaledHeaderSize+d.rate))
+}
+
+// AppendBinary appends the state of the hash, as returned by
+// MarshalBinary, to b. It returns an error if the message ends with a
+// partial byte written by WriteBits.
+func (d *state) AppendBinary(b []byte) ([]byte, error) {
+	if d.state == spongeAbsorbing && d.ntrailing != 0 {
+		return nil, errMarshalBits
+	}
+	b = append(b, marshalMagic...)
+	b = append(b, marshalVersion, byte(d.rate), d.dsbyte, byte(d.rounds))
+	b = append(b, byte(d.outputLen>>24), byte(d.outputLen>>16), byte(d.outputLen>>8), byte(d.outputLen))
+	b = append(b, byte(d.state))
+	switch d.state {
+	case spongeAbsorbing:
+		// The position is the amount of buffered input, which follows
+		// the lanes.
+		b = append(b, byte(len(d.buf)))
+	case spongeSqueezing:
+		// The position is the amount of output already read from the
+		// current block, which is recomputed from the lanes.
+		b = append(b, byte(d.rate-len(d.buf)))
+	}
+	for _, lane := range d.a {
+		var l [8]byte
+		binary.LittleEndian.PutUint64(l[:], storeLane(lane))
+		b = append(b, l[:]...)
+	}
+	if d.state == spongeAbsorbing {
+		b = append(b, d.buf...)
+	}
+	return b, nil
+}
+
+// UnmarshalBinary restores a state returned by MarshalBinary. It returns
+// an error if the state was saved by a different function, in which case
+// the hash is left unchanged.
+func (d *state) UnmarshalBinary(b []byte) error {
+	if len(b) < marshaledHeaderSize || string(b[:len(marshalMagic)]) != marshalMagic {
+		return errMarshalInvalid
+	}
+	b = b[len(marshalMagic):]
+	if b[0] != marshalVersion {
+		return errMarshalVersion
+	}
+	if int(b[1]) != d.rate || b[2] != d.dsbyte || int(b[3]) != d.rounds ||
+		binary.BigEndian.Uint32(b[4:]) != uint32(d.outputLen) {
+		return errMarshalMismatch
+	}
+	direction, pos := spongeDirection(b[8]), int(b[9])
+	if (direction != spongeAbsorbing && direction != spongeSqueezing) || pos >= d.rate {
+		return errMarshalInvalid
+	}
+	b = b[10:]
+
+	// Only an absorbing state is followed by its buffered input.
+	size := len(d.a) * 8
+	if direction == spongeAbsorbing {
+		size += pos
+	}
+	if len(b) != size {
+		return errMarshalInvalid
+	}
+
+	for i := range d.a {
+		d.a[i] = loadLane(binary.LittleEndian.Uint64(b))
+		b = b[8:]
+	}
+	d.state = direction
+	d.trailing, d.ntrailing = 0, 0
+	switch direction {
+	case spongeAbsorbing:
+		d.buf = d.storage[:pos]
+		copy(d.buf, b)
+	case spongeSqueezing:
+		d.buf = d.storage[:d.rate]
+		d.copyOut(d.buf)
+		d.buf = d.buf[pos:]
+	}
+	return nil
+}
+
+// MarshalBinary returns the state of the cSHAKE instance, followed by its
+// encoded function-name and customization strings.
+func (c *cshakeState) MarshalBinary() ([]byte, error) {
+	return c.AppendBinary(make([]byte, 0, marshaledHeaderSize+c.rate+len(c.initBlock)))
+}
+
+// AppendBinary appends the state of the cSHAKE instance, as returned by
+// MarshalBinary, to b.
+func (c *cshakeState) AppendBinary(b []byte) ([]byte, error) {
+	b, err := c.state.AppendBinary(b)
+	if err != nil {
+		return nil, err
+	}
+	return append(b, c.initBlock...), nil
+}
+
+// UnmarshalBinary restores a state returned by MarshalBinary. It returns
+// an error if the state was saved by a different function or with a
+// different function-name or customization string.
+func (c *cshakeState) UnmarshalBinary(b []byte) error {
+	n := len(b) - len(c.initBlock)
+	if n < 0 {
+		return errMarshalInvalid
+	}
+	if !bytes.Equal(b[n:], c.initBlock) {
+		return errMarshalMismatch
+	}
+	return c.state.UnmarshalBinary(b[:n])
+}
+
+// MarshalBinary returns the state of the SHAKE instance.
+func (f *fixedShake) MarshalBinary() ([]byte, error) { return f.d.MarshalBinary() }
+
+// AppendBinary appends the state of the SHAKE instance, as returned by
+// MarshalBinary, to b.
+func (f *fixedShake) AppendBinary(b []byte) ([]byte, error) { return f.d.AppendBinary(b) }
+
+// UnmarshalBinary restores a state returned by MarshalBinary.
+func (f *fixedShake) UnmarshalBinary(b []byte) error { return f.d.UnmarshalBinary(b) }
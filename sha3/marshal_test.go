@@ -0,0 +1,162 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+import (
+	"bytes"
+	"encoding"
+	"testing"
+)
+
+// TestMarshalAbsorbing checks that a hash restored from its marshaled
+// state, saved at any point of its input, produces the same output.
+func TestMarshalAbsorbing(t *testing.T) {
+	msg := sequentialBytes(500)
+	for name, newH := range testDigests {
+		for _, split := range []int{0, 1, 71, 72, 136, 137, 499} {
+			h := newH()
+			h.Write(msg[:split])
+			saved, err := h.(encoding.BinaryMarshaler).MarshalBinary()
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			h.Write(msg[split:])
+			want := h.Sum(nil)
+
+			h = newH()
+			h.Write([]byte("discarded"))
+			if err := h.(encoding.BinaryUnmarshaler).UnmarshalBinary(saved); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			h.Write(msg[split:])
+			if got := h.Sum(nil); !bytes.Equal(got, want) {
+				t.Errorf("%s, split at %d: got %x, want %x", name, split, got, want)
+			}
+		}
+	}
+}
+
+// TestMarshalSqueezing checks that a SHAKE instance restored while
+// squeezing continues its output where it was saved.
+func TestMarshalSqueezing(t *testing.T) {
+	for name, newH := range testShakes {
+		for _, split := range []int{0, 1, 135, 136, 168, 400} {
+			h := newH()
+			h.Write([]byte("input"))
+			first := make([]byte, split)
+			h.Read(first)
+			saved, err := h.(encoding.BinaryMarshaler).MarshalBinary()
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			want := make([]byte, 300)
+			h.Read(want)
+
+			h = newH()
+			if err := h.(encoding.BinaryUnmarshaler).UnmarshalBinary(saved); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			got := make([]byte, len(want))
+			h.Read(got)
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s, %d bytes read: got %x, want %x", name, split, got, want)
+			}
+
+			// Clone must also preserve the position in the output.
+			h = newH()
+			h.Write([]byte("input"))
+			h.Read(first)
+			h.Clone().Read(got)
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s, clone after %d bytes read: got %x, want %x", name, split, got, want)
+			}
+		}
+	}
+}
+
+// TestMarshalMismatch checks that a state is only restored into an
+// instance of the same function.
+func TestMarshalMismatch(t *testing.T) {
+	save := func(h interface{}) []byte {
+		b, err := h.(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	sha256 := save(New256())
+	for _, tc := range []struct {
+		name  string
+		h     interface{}
+		saved []byte
+	}{
+		{"SHA3-256 into SHA3-512", New512(), sha256},
+		{"SHA3-256 into SHAKE256", NewShake256(), sha256},
+		{"SHA3-256 into Keccak-256", NewLegacyKeccak256(), sha256},
+		{"SHAKE128 into TurboSHAKE128", NewTurboShake128(0x1f), save(NewShake128())},
+		{"cSHAKE128 with other S", NewCShake128(nil, []byte("a")), save(NewCShake128(nil, []byte("b")))},
+		{"truncated", New256(), sha256[:len(sha256)-1]},
+		{"bad version", New256(), append(append([]byte("sha3"), 2), sha256[5:]...)},
+		{"bad magic", New256(), append([]byte("sha4"), sha256[4:]...)},
+	} {
+		if err := tc.h.(encoding.BinaryUnmarshaler).UnmarshalBinary(tc.saved); err == nil {
+			t.Errorf("%s: UnmarshalBinary succeeded", tc.name)
+		}
+	}
+}
+
+// TestMarshalCShake checks that cSHAKE states are restored with their
+// customization.
+func TestMarshalCShake(t *testing.T) {
+	h := NewCShake256([]byte("N"), []byte("S"))
+	h.Write([]byte("input"))
+	saved, err := h.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := make([]byte, 64)
+	h.Read(want)
+
+	h = NewCShake256([]byte("N"), []byte("S"))
+	if err := h.(encoding.BinaryUnmarshaler).UnmarshalBinary(saved); err != nil {
+		t.Fatal(err)
+	}
+	got := make([]byte, 64)
+	h.Read(got)
+	if !bytes.Equal(got, want) {
+		t.Errorf("got %x, want %x", got, want)
+	}
+}
+
+// TestUnmarshalFailureKeepsState checks that a failed UnmarshalBinary
+// leaves the hash as it was, whether absorbing or squeezing.
+func TestUnmarshalFailureKeepsState(t *testing.T) {
+	other := NewShake128()
+	other.Write([]byte("other input"))
+	saved, err := other.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, read := range []int{0, 10} {
+		ref, h := NewShake128(), NewShake128()
+		for _, x := range []ShakeHash{ref, h} {
+			x.Write([]byte(testString))
+			if read > 0 {
+				x.Read(make([]byte, read))
+			}
+		}
+		for _, bad := range [][]byte{append(saved, 0), saved[:len(saved)-1]} {
+			if err := h.(encoding.BinaryUnmarshaler).UnmarshalBinary(bad); err == nil {
+				t.Fatalf("UnmarshalBinary of %d bytes succeeded", len(bad))
+			}
+		}
+		want, got := make([]byte, 64), make([]byte, 64)
+		ref.Read(want)
+		h.Read(got)
+		if !bytes.Equal(got, want) {
+			t.Errorf("after reading %d bytes: got %x, want %x", read, got, want)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+// This file provides ParallelHash, the hash function defined in NIST
+// SP 800-185 that hashes fixed-size blocks of its input independently,
+// so that they can be processed by several goroutines at once.
+
+import (
+	"runtime"
+	"sync"
+)
+
+// maxBatchPerWorker bounds the number of blocks handed to each worker in
+// a single batch, and thus the number of chaining values held at once.
+const maxBatchPerWorker = 64
+
+// A ParallelHash is an io.Writer that computes ParallelHash128 or
+// ParallelHash256. Its input is split into blocks of a fixed size, each
+// of which is hashed by SHAKE128 or SHAKE256 into a chaining value, and
+// the chaining values are hashed by cSHAKE. Whole blocks in each Write
+// are hashed concurrently by several goroutines (see SetWorkers); the
+// output does not depend on the number of goroutines or on how the input
+// is split into writes.
+type ParallelHash struct {
+	c *cshakeState // absorbs left_encode(B) and the chaining values
+
+	blockSize int
+	outputLen int // output length in bytes; zero for the XOF variants
+	leafRate  int // rate of the SHAKE instance hashing each block
+	cvLen     int // length of the chaining values in bytes
+	workers   int
+
+	buf     []byte // a partial block, not yet hashed
+	nblocks uint64 // the number of chaining values absorbed into c
+}
+
+func newParallelHash(blockSize int, S []byte, rate, cvLen, outputLen int) *ParallelHash {
+	if blockSize <= 0 {
+		panic("sha3: non-positive ParallelHash block size")
+	}
+	p := &ParallelHash{
+		c:         newCShake([]byte("ParallelHash"), S, rate),
+		blockSize: blockSize,
+		outputLen: outputLen,
+		leafRate:  rate,
+		cvLen:     cvLen,
+		workers:   runtime.GOMAXPROCS(0),
+	}
+	p.c.Write(leftEncode(uint64(blockSize)))
+	return p
+}
+
+// NewParallelHash128 creates a new ParallelHash128 instance that splits
+// its input into blocks of blockSize bytes and produces outputLen bytes
+// of output. customization is an optional customization string.
+func NewParallelHash128(blockSize, outputLen int, customization []byte) *ParallelHash {
+	if outputLen <= 0 {
+		panic("sha3: non-positive ParallelHash output length")
+	}
+	return newParallelHash(blockSize, customization, rate128, 32, outputLen)
+}
+
+// NewParallelHash256 creates a new ParallelHash256 instance that splits
+// its input into blocks of blockSize bytes and produces outputLen bytes
+// of output. customization is an optional customization string.
+func NewParallelHash256(blockSize, outputLen int, customization []byte) *ParallelHash {
+	if outputLen <= 0 {
+		panic("sha3: non-positive ParallelHash output length")
+	}
+	return newParallelHash(blockSize, customization, rate256, 64, outputLen)
+}
+
+// NewParallelHashXOF128 creates a new ParallelHashXOF128 instance that
+// splits its input into blocks of blockSize bytes. Its output is read
+// with Read.
+func NewParallelHashXOF128(blockSize int, customization []byte) *ParallelHash {
+	return newParallelHash(blockSize, customization, rate128, 32, 0)
+}
+
+// NewParallelHashXOF256 creates a new ParallelHashXOF256 instance that
+// splits its input into blocks of blockSize bytes. Its output is read
+// with Read.
+func NewParallelHashXOF256(blockSize int, customization []byte) *ParallelHash {
+	return newParallelHash(blockSize, customization, rate256, 64, 0)
+}
+
+// SetWorkers sets the maximum number of goroutines used to hash blocks.
+// If n is less than one, the value of runtime.GOMAXPROCS is used, which is
+// also the default.
+func (p *ParallelHash) SetWorkers(n int) {
+	if n < 1 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	p.workers = n
+}
+
+// BlockSize returns the size of the blocks that are hashed independently.
+func (p *ParallelHash) BlockSize() int { return p.blockSize }
+
+// Size returns the output size of ParallelHash in bytes, or zero for the
+// XOF variants.
+func (p *ParallelHash) Size() int { return p.outputLen }
+
+// Reset resets the ParallelHash to its initial state.
+func (p *ParallelHash) Reset() {
+	p.c.Reset()
+	p.c.Write(leftEncode(uint64(p.blockSize)))
+	p.buf = p.buf[:0]
+	p.nblocks = 0
+}
+
+// Clone returns a copy of the ParallelHash in its current state.
+func (p *ParallelHash) Clone() *ParallelHash {
+	ret := *p
+	ret.c = p.c.Clone().(*cshakeState)
+	ret.buf = append([]byte(nil), p.buf...)
+	return &ret
+}
+
+// hashBlock writes the chaining value of block into cv.
+func (p *ParallelHash) hashBlock(cv, block []byte) {
+	leaf := state{rate: p.leafRate, dsbyte: dsbyteShake}
+	leaf.Write(block)
+	leaf.Read(cv)
+}
+
+// hashBlocks hashes the whole blocks in data and absorbs their chaining
+// values in order.
+func (p *ParallelHash) hashBlocks(data []byte) {
+	cvs := hashLeaves(data, p.blockSize, p.cvLen, p.workers, p.hashBlock)
+	p.c.Write(cvs)
+	p.nblocks += uint64(len(data) / p.blockSize)
+}
+
+// hashLeaves hashes the consecutive leaves of leafSize bytes in data with
+// hashLeaf, spreading them over up to workers goroutines, and returns
+// their chaining values of cvLen bytes each, in order.
+func hashLeaves(data []byte, leafSize, cvLen, workers int, hashLeaf func(cv, leaf []byte)) []byte {
+	n := len(data) / leafSize
+	cvs := make([]byte, n*cvLen)
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			hashLeaf(cvs[i*cvLen:(i+1)*cvLen], data[i*leafSize:(i+1)*leafSize])
+		}
+		return cvs
+	}
+
+	// Give each worker a contiguous run of leaves.
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func(start, end int) {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				hashLeaf(cvs[i*cvLen:(i+1)*cvLen], data[i*leafSize:(i+1)*leafSize])
+			}
+		}(w*n/workers, (w+1)*n/workers)
+	}
+	wg.Wait()
+	return cvs
+}
+
+// Write absorbs more data into the hash's state. It panics if input is
+// written to it after output has been read from it.
+func (p *ParallelHash) Write(in []byte) (written int, err error) {
+	if p.c.state.state != spongeAbsorbing {
+		panic("sha3: write to sponge after read")
+	}
+	written = len(in)
+
+	if len(p.buf) > 0 {
+		// Complete the partial block first.
+		todo := p.blockSize - len(p.buf)
+		if todo > len(in) {
+			todo = len(in)
+		}
+		p.buf = append(p.buf, in[:todo]...)
+		in = in[todo:]
+		if len(p.buf) < p.blockSize {
+			return
+		}
+		p.hashBlocks(p.buf)
+		p.buf = p.buf[:0]
+	}
+
+	// Hash whole blocks straight from the input, a batch at a time.
+	for len(in) >= p.blockSize {
+		n := len(in) / p.blockSize
+		if limit := p.workers * maxBatchPerWorker; n > limit {
+			n = limit
+		}
+		p.hashBlocks(in[:n*p.blockSize])
+		in = in[n*p.blockSize:]
+	}
+
+	p.buf = append(p.buf, in...)
+	return
+}
+
+// finish hashes the final partial block, if any, and absorbs the number
+// of blocks and the output length L, in bits, into a copy of the state.
+func (p *ParallelHash) finish(L uint64) *state {
+	dup := p.c.clone()
+	nblocks := p.nblocks
+	if len(p.buf) > 0 {
+		cv := make([]byte, p.cvLen)
+		p.hashBlock(cv, p.buf)
+		dup.Write(cv)
+		nblocks++
+	}
+	dup.Write(rightEncode(nblocks))
+	dup.Write(rightEncode(L))
+	return dup
+}
+
+// Sum appends Size bytes of output for the data written so far to in.
+// It does not change the underlying hash state. It panics for the XOF
+// variants, whose output is read with Read.
+func (p *ParallelHash) Sum(in []byte) []byte {
+	if p.outputLen == 0 {
+		panic("sha3: Sum called on ParallelHashXOF")
+	}
+	dup := p.finish(uint64(p.outputLen) * 8)
+	hash := make([]byte, p.outputLen)
+	dup.Read(hash)
+	dup.Wipe()
+	return append(in, hash...)
+}
+
+// Read squeezes an arbitrary number of bytes of ParallelHashXOF output.
+// No more data can be written after output has been read. It panics for
+// the fixed-output-length variants, whose output is produced by Sum.
+func (p *ParallelHash) Read(out []byte) (n int, err error) {
+	if p.outputLen != 0 {
+		panic("sha3: Read called on fixed-output-length ParallelHash")
+	}
+	if p.c.state.state == spongeAbsorbing {
+		p.c.state = p.finish(0)
+		p.buf = p.buf[:0]
+	}
+	return p.c.Read(out)
+}
+
+// ParallelHash128 returns outputLen bytes of the ParallelHash128 of data,
+// split into blocks of blockSize bytes, with customization string
+// customization.
+func ParallelHash128(data []byte, blockSize, outputLen int, customization []byte) []byte {
+	p := NewParallelHash128(blockSize, outputLen, customization)
+	p.Write(data)
+	return p.Sum(nil)
+}
+
+// ParallelHash256 returns outputLen bytes of the ParallelHash256 of data,
+// split into blocks of blockSize bytes, with customization string
+// customization.
+func ParallelHash256(data []byte, blockSize, outputLen int, customization []byte) []byte {
+	p := NewParallelHash256(blockSize, outputLen, customization)
+	p.Write(data)
+	return p.Sum(nil)
+}
@@ -0,0 +1,50 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+// This file exports the Keccak-p[1600, n] permutations, for use in
+// constructions other than the sponge functions of this package.
+
+import (
+	"encoding/binary"
+)
+
+// KeccakF1600 applies Keccak-f[1600], the 24-round permutation used by
+// SHA-3 and SHAKE, to the state a. Lane (x, y) of the state, as numbered
+// in FIPS 202, is a[x+5*y].
+func KeccakF1600(a *[25]uint64) {
+	keccakF1600(a)
+}
+
+// KeccakP1600 applies Keccak-p[1600, rounds] to the state a: the last
+// rounds rounds of Keccak-f[1600], so that KeccakP1600(a, 24) is
+// KeccakF1600(a) and KeccakP1600(a, 12) is the permutation of TurboSHAKE
+// and KangarooTwelve. It panics if rounds is not between 1 and 24.
+func KeccakP1600(a *[25]uint64, rounds int) {
+	if rounds < 1 || rounds > len(rc) {
+		panic("sha3: Keccak-p[1600] round count out of range")
+	}
+	keccakP1600(a, rounds)
+}
+
+// KeccakF1600Bytes applies Keccak-f[1600] to the state b, whose lanes are
+// stored as consecutive little-endian 64-bit words as in FIPS 202.
+func KeccakF1600Bytes(b *[200]byte) {
+	KeccakP1600Bytes(b, len(rc))
+}
+
+// KeccakP1600Bytes applies Keccak-p[1600, rounds] to the state b, whose
+// lanes are stored as consecutive little-endian 64-bit words as in FIPS
+// 202. It panics if rounds is not between 1 and 24.
+func KeccakP1600Bytes(b *[200]byte, rounds int) {
+	var a [25]uint64
+	for i := range a {
+		a[i] = binary.LittleEndian.Uint64(b[8*i:])
+	}
+	KeccakP1600(&a, rounds)
+	for i := range a {
+		binary.LittleEndian.PutUint64(b[8*i:], a[i])
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// keccakZeroStateF1600 holds the states obtained by applying
+// Keccak-f[1600] once and twice to the all-zero state, from the
+// intermediate values published by the Keccak team.
+var keccakZeroStateF1600 = [2][25]uint64{
+	{
+		0xF1258F7940E1DDE7, 0x84D5CCF933C0478A, 0xD598261EA65AA9EE, 0xBD1547306F80494D,
+		0x8B284E056253D057, 0xFF97A42D7F8E6FD4, 0x90FEE5A0A44647C4, 0x8C5BDA0CD6192E76,
+		0xAD30A6F71B19059C, 0x30935AB7D08FFC64, 0xEB5AA93F2317D635, 0xA9A6E6260D712103,
+		0x81A57C16DBCF555F, 0x43B831CD0347C826, 0x01F22F1A11A5569F, 0x05E5635A21D9AE61,
+		0x64BEFEF28CC970F2, 0x613670957BC46611, 0xB87C5A554FD00ECB, 0x8C3EE88A1CCF32C8,
+		0x940C7922AE3A2614, 0x1841F924A2C509E4, 0x16F53526E70465C2, 0x75F644E97F30A13B,
+		0xEAF1FF7B5CECA249,
+	},
+	{
+		0x2D5C954DF96ECB3C, 0x6A332CD07057B56D, 0x093D8D1270D76B6C, 0x8A20D9B25569D094,
+		0x4F9C4F99E5E7F156, 0xF957B9A2DA65FB38, 0x85773DAE1275AF0D, 0xFAF4F247C3D810F7,
+		0x1F1B9EE6F79A8759, 0xE4FECC0FEE98B425, 0x68CE61B6B9CE68A1, 0xDEEA66C4BA8F974F,
+		0x33C43D836EAFB1F5, 0xE00654042719DBD9, 0x7CF8A9F009831265, 0xFD5449A6BF174743,
+		0x97DDAD33D8994B40, 0x48EAD5FC5D0BE774, 0xE3B8C8EE55B7B03C, 0x91A0226E649E42E9,
+		0x900E3129E7BADD7B, 0x202A9EC5FAA3CCE8, 0x5B3402464E1C3DB6, 0x609F4E62A44C1059,
+		0x20D06CD26A8FBF5C,
+	},
+}
+
+// keccakZeroStateP1600x12 is the state obtained by applying
+// Keccak-p[1600, 12] to the all-zero state.
+var keccakZeroStateP1600x12 = [25]uint64{
+	0x8E5E5438B9A78617, 0xD9CD6A50F259D01E, 0x87B8E7C652A91F35, 0x1093E067CDE4E0C5,
+	0xB033AB90F2D95A45, 0xE0A72F72A8DD1A45, 0xC53780AA14672F9C, 0x3EDD47F50051071D,
+	0xB3A31D310C178ACC, 0x79B586A59257AAA0, 0xBC4A7C3DB3B1F99B, 0x68874063E68A6793,
+	0x5C6C03332E0E2566, 0x9CAA1202B9F030DA, 0x5F3B9A782BCF7A9F, 0xE536C1E061AE7923,
+	0x6DE9B618B73C87EC, 0x2ABED1F170918AC2, 0x6AABBD53DAED24B7, 0xBFC1416A2C2EE15A,
+	0xC6CFE036B90952AF, 0x45503617DC7060D7, 0x625611B2C29F7AE4, 0xD43671DB2C30647A,
+	0xCFFD0D76222CA01C,
+}
+
+func TestKeccakF1600(t *testing.T) {
+	var a [25]uint64
+	for i, want := range keccakZeroStateF1600 {
+		KeccakF1600(&a)
+		if a != want {
+			t.Errorf("after %d applications: got %016X, want %016X", i+1, a, want)
+		}
+	}
+
+	var b [200]byte
+	for i, want := range keccakZeroStateF1600 {
+		KeccakF1600Bytes(&b)
+		for j := range want {
+			if got := binary.LittleEndian.Uint64(b[8*j:]); got != want[j] {
+				t.Errorf("bytes after %d applications: lane %d is %016X, want %016X", i+1, j, got, want[j])
+			}
+		}
+	}
+}
+
+func TestKeccakP1600(t *testing.T) {
+	var a [25]uint64
+	KeccakP1600(&a, 12)
+	if a != keccakZeroStateP1600x12 {
+		t.Errorf("got %016X, want %016X", a, keccakZeroStateP1600x12)
+	}
+
+	// Keccak-p[1600, 24] is Keccak-f[1600].
+	a = [25]uint64{}
+	KeccakP1600(&a, 24)
+	if a != keccakZeroStateF1600[0] {
+		t.Errorf("Keccak-p[1600, 24]: got %016X, want %016X", a, keccakZeroStateF1600[0])
+	}
+
+	for _, rounds := range []int{0, -1, 25} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("KeccakP1600 with %d rounds did not panic", rounds)
+				}
+			}()
+			KeccakP1600(&a, rounds)
+		}()
+	}
+}
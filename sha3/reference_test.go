@@ -0,0 +1,324 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+// This file contains a slow reference implementation of FIPS 202 that
+// follows the text of the standard as literally as possible: the state is
+// an array of 5x5x64 bits, the step mappings are those of section 3.2 and
+// messages are strings of bits padded by pad10*1. It shares no code with
+// the rest of the package, and serves as an oracle for the fuzz targets
+// below, which compare it with the optimized implementation.
+//
+// Run the fuzz targets with, for instance,
+//
+//	go test -run - -fuzz FuzzReferenceHash
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// refState is the state array A of section 3.1.2 of FIPS 202: A[x][y][z]
+// is the bit at column x, row y and position z, each 0 or 1.
+type refState [5][5][64]byte
+
+// refFromBits is the conversion of section 3.1.2 from a string of 1600
+// bits to a state array.
+func refFromBits(S []byte) *refState {
+	var A refState
+	for x := 0; x < 5; x++ {
+		for y := 0; y < 5; y++ {
+			for z := 0; z < 64; z++ {
+				A[x][y][z] = S[64*(5*y+x)+z]
+			}
+		}
+	}
+	return &A
+}
+
+// refToBits is the conversion of section 3.1.3 from a state array to a
+// string of 1600 bits.
+func refToBits(A *refState) []byte {
+	S := make([]byte, 1600)
+	for x := 0; x < 5; x++ {
+		for y := 0; y < 5; y++ {
+			for z := 0; z < 64; z++ {
+				S[64*(5*y+x)+z] = A[x][y][z]
+			}
+		}
+	}
+	return S
+}
+
+// mod returns a mod n in [0, n).
+func mod(a, n int) int {
+	return ((a % n) + n) % n
+}
+
+// refTheta is Algorithm 1 of FIPS 202.
+func refTheta(A *refState) *refState {
+	var C, D [5][64]byte
+	for x := 0; x < 5; x++ {
+		for z := 0; z < 64; z++ {
+			C[x][z] = A[x][0][z] ^ A[x][1][z] ^ A[x][2][z] ^ A[x][3][z] ^ A[x][4][z]
+		}
+	}
+	for x := 0; x < 5; x++ {
+		for z := 0; z < 64; z++ {
+			D[x][z] = C[mod(x-1, 5)][z] ^ C[mod(x+1, 5)][mod(z-1, 64)]
+		}
+	}
+	var B refState
+	for x := 0; x < 5; x++ {
+		for y := 0; y < 5; y++ {
+			for z := 0; z < 64; z++ {
+				B[x][y][z] = A[x][y][z] ^ D[x][z]
+			}
+		}
+	}
+	return &B
+}
+
+// refRho is Algorithm 2 of FIPS 202.
+func refRho(A *refState) *refState {
+	var B refState
+	B[0][0] = A[0][0]
+	x, y := 1, 0
+	for t := 0; t < 24; t++ {
+		for z := 0; z < 64; z++ {
+			B[x][y][z] = A[x][y][mod(z-(t+1)*(t+2)/2, 64)]
+		}
+		x, y = y, mod(2*x+3*y, 5)
+	}
+	return &B
+}
+
+// refPi is Algorithm 3 of FIPS 202.
+func refPi(A *refState) *refState {
+	var B refState
+	for x := 0; x < 5; x++ {
+		for y := 0; y < 5; y++ {
+			B[x][y] = A[mod(x+3*y, 5)][x]
+		}
+	}
+	return &B
+}
+
+// refChi is Algorithm 4 of FIPS 202.
+func refChi(A *refState) *refState {
+	var B refState
+	for x := 0; x < 5; x++ {
+		for y := 0; y < 5; y++ {
+			for z := 0; z < 64; z++ {
+				B[x][y][z] = A[x][y][z] ^ ((A[mod(x+1, 5)][y][z] ^ 1) & A[mod(x+2, 5)][y][z])
+			}
+		}
+	}
+	return &B
+}
+
+// refRC is Algorithm 5 of FIPS 202, the bit rc(t) of the round constants.
+func refRC(t int) byte {
+	if mod(t, 255) == 0 {
+		return 1
+	}
+	R := []byte{1, 0, 0, 0, 0, 0, 0, 0}
+	for i := 1; i <= mod(t, 255); i++ {
+		R = append([]byte{0}, R...)
+		R[0] ^= R[8]
+		R[4] ^= R[8]
+		R[5] ^= R[8]
+		R[6] ^= R[8]
+		R = R[:8]
+	}
+	return R[0]
+}
+
+// refIota is Algorithm 6 of FIPS 202.
+func refIota(A *refState, ir int) *refState {
+	B := *A
+	var RC [64]byte
+	for j := 0; j <= 6; j++ {
+		RC[1<<uint(j)-1] = refRC(j + 7*ir)
+	}
+	for z := 0; z < 64; z++ {
+		B[0][0][z] ^= RC[z]
+	}
+	return &B
+}
+
+// refKeccakP is Algorithm 7 of FIPS 202, Keccak-p[1600, nr], on a string
+// of 1600 bits.
+func refKeccakP(S []byte, nr int) []byte {
+	A := refFromBits(S)
+	for ir := 24 - nr; ir <= 23; ir++ {
+		A = refIota(refChi(refPi(refRho(refTheta(A)))), ir)
+	}
+	return refToBits(A)
+}
+
+// refPad is the pad10*1 rule of section 5.1 of FIPS 202.
+func refPad(x, m int) []byte {
+	j := mod(-m-2, x)
+	P := make([]byte, j+2)
+	P[0], P[j+1] = 1, 1
+	return P
+}
+
+// refSponge is Algorithm 8 of FIPS 202, SPONGE[Keccak-p[1600, nr],
+// pad10*1, r](N, d), with the rate r in bits.
+func refSponge(nr, r int, N []byte, d int) []byte {
+	P := append(append([]byte(nil), N...), refPad(r, len(N))...)
+	S := make([]byte, 1600)
+	for i := 0; i < len(P); i += r {
+		for k := 0; k < r; k++ {
+			S[k] ^= P[i+k]
+		}
+		S = refKeccakP(S, nr)
+	}
+	var Z []byte
+	for {
+		Z = append(Z, S[:r]...)
+		if d <= len(Z) {
+			return Z[:d]
+		}
+		S = refKeccakP(S, nr)
+	}
+}
+
+// refBits returns the first n bits of b, each byte contributing its bits
+// from the least significant one as in section B.1 of FIPS 202.
+func refBits(b []byte, n int) []byte {
+	bits := make([]byte, n)
+	for i := range bits {
+		bits[i] = b[i/8] >> uint(i%8) & 1
+	}
+	return bits
+}
+
+// refBytes is the inverse of refBits; the unused bits of the last byte
+// are zero.
+func refBytes(bits []byte) []byte {
+	b := make([]byte, (len(bits)+7)/8)
+	for i, bit := range bits {
+		b[i/8] |= bit << uint(i%8)
+	}
+	return b
+}
+
+// refFunc is a function of FIPS 202, or a sponge built the same way with
+// other parameters: the message is followed by the bits of suffix, and
+// output is squeezed from the sponge with the given rate and rounds.
+type refFunc struct {
+	name      string
+	rateBits  int
+	rounds    int
+	suffix    []byte
+	outputLen int // bytes of output of the hash, or zero for an XOF
+	newHash   func() *state
+}
+
+var refFuncs = []refFunc{
+	{"SHA3-224", 1152, 24, []byte{0, 1}, 28, func() *state { return New224().(*state) }},
+	{"SHA3-256", 1088, 24, []byte{0, 1}, 32, func() *state { return New256().(*state) }},
+	{"SHA3-384", 832, 24, []byte{0, 1}, 48, func() *state { return New384().(*state) }},
+	{"SHA3-512", 576, 24, []byte{0, 1}, 64, func() *state { return New512().(*state) }},
+	{"SHAKE128", 1344, 24, []byte{1, 1, 1, 1}, 0, func() *state { return NewShake128().(*state) }},
+	{"SHAKE256", 1088, 24, []byte{1, 1, 1, 1}, 0, func() *state { return NewShake256().(*state) }},
+	{"Keccak-256", 1088, 24, nil, 32, func() *state { return NewLegacyKeccak256().(*state) }},
+	{"TurboSHAKE128", 1344, 12, []byte{1, 1, 1, 1, 1}, 0, func() *state { return NewTurboShake128(0x3f).(*state) }},
+}
+
+// hash returns d bits of the output of f for the message M of nbits bits.
+func (f *refFunc) hash(M []byte, nbits, d int) []byte {
+	N := append(refBits(M, nbits), f.suffix...)
+	return refBytes(refSponge(f.rounds, f.rateBits, N, d))
+}
+
+// TestReference checks the reference implementation against examples
+// published by NIST.
+func TestReference(t *testing.T) {
+	for _, tc := range []struct {
+		f     *refFunc
+		msg   string
+		nbits int
+		want  string
+	}{
+		{&refFuncs[1], "", 0, "a7ffc6f8bf1ed76651c14756a061d662f580ff4de43b49fa82d80a4b80f8434a"},
+		{&refFuncs[1], "616263", 24, "3a985da74fe225b2045c172d6bd390bd855f086e3e9d525b46bfe24511431532"},
+		{&refFuncs[1], "13", 5, "7b0047cf5a456882363cbf0fb05322cf65f4b7059a46365e830132e3b5d957af"},
+		{&refFuncs[4], "", 0, "7f9c2ba4e88f827d616045507605853ed73b8093f6efbc88eb1a6eacfa66ef26"},
+	} {
+		msg, _ := hex.DecodeString(tc.msg)
+		if got := hex.EncodeToString(tc.f.hash(msg, tc.nbits, 256)); got != tc.want {
+			t.Errorf("%s(%q, %d bits): got %s, want %s", tc.f.name, tc.msg, tc.nbits, got, tc.want)
+		}
+	}
+}
+
+// FuzzReferenceHash compares the functions of refFuncs with their
+// reference implementation, for messages of any number of bits written in
+// two parts, and outputs of any number of bits read in two parts.
+func FuzzReferenceHash(f *testing.F) {
+	f.Add(uint8(0), []byte("abc"), uint8(0), uint16(1), uint16(0))
+	f.Add(uint8(1), bytes.Repeat([]byte{0xa5}, 136), uint8(5), uint16(100), uint16(0))
+	f.Add(uint8(3), bytes.Repeat([]byte{0x3c}, 72), uint8(3), uint16(71), uint16(0))
+	f.Add(uint8(4), bytes.Repeat([]byte{0xff}, 168), uint8(4), uint16(0), uint16(1350))
+	f.Add(uint8(5), bytes.Repeat([]byte{0x01}, 300), uint8(1), uint16(135), uint16(2177))
+	f.Add(uint8(6), []byte{}, uint8(0), uint16(0), uint16(0))
+	f.Add(uint8(7), bytes.Repeat([]byte{0x5a}, 170), uint8(6), uint16(168), uint16(13))
+	f.Fuzz(func(t *testing.T, fn uint8, msg []byte, trailing uint8, split, outBits uint16) {
+		ref := &refFuncs[int(fn)%len(refFuncs)]
+		if len(msg) > 1000 {
+			msg = msg[:1000]
+		}
+		// The last byte of msg contributes trailing%8 bits, or all of
+		// them if that is zero.
+		nbits := 8 * len(msg)
+		if r := int(trailing % 8); r != 0 && len(msg) > 0 {
+			nbits -= 8 - r
+		}
+		n := int(split) % (nbits/8 + 1)
+
+		h := ref.newHash()
+		h.Write(msg[:n])
+		h.WriteBits(msg[n:], nbits-8*n)
+
+		var got, want []byte
+		if ref.outputLen != 0 {
+			got = h.Sum(nil)
+			want = ref.hash(msg, nbits, 8*ref.outputLen)
+		} else {
+			d := int(outBits) % 3000
+			first := int(split) % (d/8 + 1)
+			got = make([]byte, (d+7)/8)
+			h.Read(got[:first])
+			h.ReadBits(got[first:], d-8*first)
+			want = ref.hash(msg, nbits, d)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s(%x, %d bits): got %x, want %x", ref.name, msg, nbits, got, want)
+		}
+	})
+}
+
+// FuzzReferencePermutation compares Keccak-p[1600, rounds] with its
+// reference implementation.
+func FuzzReferencePermutation(f *testing.F) {
+	f.Add(make([]byte, 200), uint8(24))
+	f.Add(bytes.Repeat([]byte{0xff}, 200), uint8(12))
+	f.Add(sequentialBytes(200), uint8(1))
+	f.Fuzz(func(t *testing.T, in []byte, rounds uint8) {
+		var b [200]byte
+		copy(b[:], in)
+		nr := int(rounds)%24 + 1
+		want := refBytes(refKeccakP(refBits(b[:], 1600), nr))
+		KeccakP1600Bytes(&b, nr)
+		if !bytes.Equal(b[:], want) {
+			t.Errorf("Keccak-p[1600, %d](%x): got %x, want %x", nr, in, b, want)
+		}
+	})
+}
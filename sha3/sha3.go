@@ -26,10 +26,15 @@ const (
 
 type state struct {
 	// Generic sponge components.
-	a    [25]uint64 // main state of the hash
+	a    [25]uint64 // main state of the hash, in the form given by loadLane
 	buf  []byte     // points into storage
 	rate int        // the number of bytes of state to use
 
+	// rounds is the number of rounds of the Keccak-p[1600, rounds]
+	// permutation applied to the state. The zero value selects the full
+	// 24 rounds of Keccak-f[1600] used by SHA-3 and SHAKE.
+	rounds int
+
 	// dsbyte contains the "domain separation" value and the first bit of
 	// the padding. In sections 6.1 and 6.2 of [1], the SHA-3 and SHAKE
 	// functions are defined with bits appended to the message: SHA-3
@@ -45,6 +50,12 @@ type state struct {
 	dsbyte  byte
 	storage [maxRate]byte
 
+	// trailing holds the last ntrailing bits of a message whose length is
+	// not a multiple of eight bits, absorbed with WriteBits. They are
+	// merged with dsbyte when the message is padded.
+	trailing  byte
+	ntrailing int
+
 	// Specific to SHA-3 and SHAKE.
 	fixedOutput bool            // whether this is a fixed-ouput-length instance
 	outputLen   int             // the default output size in bytes
@@ -66,6 +77,7 @@ func (d *state) Reset() {
 	}
 	d.state = spongeAbsorbing
 	d.buf = d.storage[:0]
+	d.trailing, d.ntrailing = 0, 0
 }
 
 func (d *state) clone() *state {
@@ -73,12 +85,21 @@ func (d *state) clone() *state {
 	if ret.state == spongeAbsorbing {
 		ret.buf = ret.storage[:len(ret.buf)]
 	} else {
-		ret.buf = ret.storage[d.rate-cap(d.buf) : d.rate]
+		ret.buf = ret.storage[d.rate-len(d.buf) : d.rate]
 	}
 
 	return &ret
 }
 
+// keccak applies the sponge's permutation to its state.
+func (d *state) keccak() {
+	rounds := d.rounds
+	if rounds == 0 {
+		rounds = len(rc)
+	}
+	permuteLanes(&d.a, rounds)
+}
+
 // xorIn xors a buffer into the state, byte-swapping to
 // little-endian as necessary; it returns the number of bytes
 // copied, including any zeros appended to the bytestring.
@@ -87,7 +108,7 @@ func (d *state) xorIn(buf []byte) {
 
 	for i := 0; i < n; i++ {
 		a := binary.LittleEndian.Uint64(buf)
-		d.a[i] ^= a
+		d.a[i] ^= loadLane(a)
 		buf = buf[8:]
 	}
 	if len(buf) != 0 {
@@ -96,19 +117,27 @@ func (d *state) xorIn(buf []byte) {
 		for i, v := range buf {
 			a |= uint64(v) << uint64(8*i)
 		}
-		d.a[n] ^= a
+		d.a[n] ^= loadLane(a)
 	}
 }
 
-// copyOut copies ulint64s to a byte buffer.
+// copyOut copies ulint64s to a byte buffer, including the leading
+// bytes of the last one if len(b) is not a multiple of 8.
 func (d *state) copyOut(b []byte) {
-	for i := 0; len(b) >= 8; i++ {
-		binary.LittleEndian.PutUint64(b, d.a[i])
+	i := 0
+	for ; len(b) >= 8; i++ {
+		binary.LittleEndian.PutUint64(b, storeLane(d.a[i]))
 		b = b[8:]
 	}
+	if len(b) > 0 {
+		lane := storeLane(d.a[i])
+		for j := range b {
+			b[j] = byte(lane >> uint(8*j))
+		}
+	}
 }
 
-// permute applies the KeccakF-1600 permutation. It handles
+// permute applies the sponge's permutation. It handles
 // any input-output buffering.
 func (d *state) permute() {
 	switch d.state {
@@ -117,11 +146,11 @@ func (d *state) permute() {
 		// before applying the permutation.
 		d.xorIn(d.buf)
 		d.buf = d.storage[:0]
-		keccakF1600(&d.a)
+		d.keccak()
 	case spongeSqueezing:
 		// If we're squeezing, we need to apply the permutatin before
 		// copying more output.
-		keccakF1600(&d.a)
+		d.keccak()
 		d.buf = d.storage[:d.rate]
 		d.copyOut(d.buf)
 	}
@@ -137,7 +166,25 @@ func (d *state) padAndPermute(dsbyte byte) {
 	// at least one byte of space in d.buf because, if it were full,
 	// permute would have been called to empty it. dsbyte also contains the
 	// first one bit for the padding. See the comment in the state struct.
-	d.buf = append(d.buf, dsbyte)
+	//
+	// The domain-separator bits follow the trailing bits of the message,
+	// if any, and may then spill over into a second byte.
+	pad := uint16(d.trailing) | uint16(dsbyte)<<uint(d.ntrailing)
+	d.trailing, d.ntrailing = 0, 0
+	last := byte(pad)
+	if pad > 0xff {
+		d.buf = append(d.buf, byte(pad))
+		if len(d.buf) == d.rate {
+			d.permute()
+		}
+		last = byte(pad >> 8)
+	}
+	d.buf = append(d.buf, last)
+	if len(d.buf) == d.rate && last&0x80 != 0 {
+		// The first one bit of the padding is the last bit of the block,
+		// so the final one bit goes at the end of another block.
+		d.permute()
+	}
 	zerosStart := len(d.buf)
 	d.buf = d.storage[:d.rate]
 	for i := zerosStart; i < d.rate; i++ {
@@ -160,6 +207,9 @@ func (d *state) Write(p []byte) (written int, err error) {
 	if d.state != spongeAbsorbing {
 		panic("sha3: write to sponge after read")
 	}
+	if d.ntrailing != 0 {
+		panic("sha3: write to sponge after partial byte")
+	}
 	if d.buf == nil {
 		d.buf = d.storage[:0]
 	}
@@ -170,7 +220,7 @@ func (d *state) Write(p []byte) (written int, err error) {
 			// The fast path; absorb a full "rate" bytes of input and apply the permutation.
 			d.xorIn(p[:d.rate])
 			p = p[d.rate:]
-			keccakF1600(&d.a)
+			d.keccak()
 		} else {
 			// The slow path; buffer the input until we can fill the sponge, and then xor it in.
 			todo := d.rate - len(d.buf)
@@ -222,5 +272,6 @@ func (d *state) Sum(in []byte) []byte {
 	dup := d.clone()
 	hash := make([]byte, dup.outputLen)
 	dup.Read(hash)
+	dup.Wipe()
 	return append(in, hash...)
 }
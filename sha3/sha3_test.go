@@ -16,6 +16,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"hash"
+	"io"
 	"os"
 	"strings"
 	"testing"
@@ -26,14 +27,6 @@ const (
 	katFilename = "keccakKats.json.deflate"
 )
 
-// Internal-use instances of SHAKE used to test against KATs.
-func newHashShake128() hash.Hash {
-	return &state{rate: 168, dsbyte: 0x1f, outputLen: 512}
-}
-func newHashShake256() hash.Hash {
-	return &state{rate: 136, dsbyte: 0x1f, outputLen: 512}
-}
-
 // testDigests contains functions returning hash.Hash instances
 // with output-length equal to the KAT length for both SHA-3 and
 // SHAKE instances.
@@ -42,8 +35,8 @@ var testDigests = map[string]func() hash.Hash{
 	"SHA3-256": New256,
 	"SHA3-384": New384,
 	"SHA3-512": New512,
-	"SHAKE128": newHashShake128,
-	"SHAKE256": newHashShake256,
+	"SHAKE128": func() hash.Hash { return NewShake128Hash(512) },
+	"SHAKE256": func() hash.Hash { return NewShake256Hash(512) },
 }
 
 // testShakes contains functions returning ShakeHash instances for
@@ -194,6 +187,614 @@ func TestSqueezing(t *testing.T) {
 	}
 }
 
+// TestShakeHash checks the hash.Hash interface of SHAKE with a fixed
+// output length, and the ShakeSum128N and ShakeSum256N functions.
+func TestShakeHash(t *testing.T) {
+	msg := sequentialBytes(1000)
+	for _, v := range []struct {
+		name    string
+		newHash func(int) hash.Hash
+		sum     func([]byte, int) []byte
+		newH    func() ShakeHash
+	}{
+		{"SHAKE128", NewShake128Hash, ShakeSum128N, NewShake128},
+		{"SHAKE256", NewShake256Hash, ShakeSum256N, NewShake256},
+	} {
+		for _, n := range []int{1, 32, 200, 1000} {
+			want := make([]byte, n)
+			ref := v.newH()
+			ref.Write(msg)
+			ref.Read(want)
+
+			h := v.newHash(n)
+			if h.Size() != n {
+				t.Errorf("%s: Size() = %d, want %d", v.name, h.Size(), n)
+			}
+			h.Write(msg[:100])
+			h.Write(msg[100:])
+			if got := h.Sum([]byte("prefix")); !bytes.Equal(got, append([]byte("prefix"), want...)) {
+				t.Errorf("%s(%d): Sum got %x, want %x", v.name, n, got, want)
+			}
+			if got := h.Sum(nil); !bytes.Equal(got, want) {
+				t.Errorf("%s(%d): second Sum got %x, want %x", v.name, n, got, want)
+			}
+			if got := v.sum(msg, n); !bytes.Equal(got, want) {
+				t.Errorf("%s(%d): ShakeSum got %x, want %x", v.name, n, got, want)
+			}
+		}
+		if _, ok := v.newHash(32).(io.Reader); ok {
+			t.Errorf("%s: the fixed-length hash.Hash has a Read method", v.name)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("NewShake256Hash(0) did not panic")
+		}
+	}()
+	NewShake256Hash(0)
+}
+
+// cShakeSamples are the cSHAKE sample values published by NIST for
+// SP 800-185, at http://csrc.nist.gov/groups/ST/toolkit/examples.html
+var cShakeSamples = []struct {
+	name      string
+	newCShake func(N, S []byte) ShakeHash
+	dataLen   int
+	N, S      string
+	digest    string
+}{
+	{"cSHAKE128", NewCShake128, 4, "", "Email Signature",
+		"C1C36925B6409A04F1B504FCBCA9D82B4017277CB5ED2B2065FC1D3814D5AAF5"},
+	{"cSHAKE128", NewCShake128, 200, "", "Email Signature",
+		"C5221D50E4F822D96A2E8881A961420F294B7B24FE3D2094BAED2C6524CC166B"},
+	{"cSHAKE256", NewCShake256, 4, "", "Email Signature",
+		"D008828E2B80AC9D2218FFEE1D070C48B8E4C87BFF32C9699D5B6896EEE0EDD1" +
+			"64020E2BE0560858D9C00C037E34A96937C561A74C412BB4C746469527281C8C"},
+	{"cSHAKE256", NewCShake256, 200, "", "Email Signature",
+		"07DC27B11E51FBAC75BC7B3C1D983E8B4B85FB1DEFAF218912AC86430273091" +
+			"727F42B17ED1DF63E8EC118F04B23633C1DFB1574C8FB55CB45DA8E25AFB092BB"},
+}
+
+// TestCShakeSamples tests cSHAKE128 and cSHAKE256 against the NIST samples,
+// both on a fresh instance and after Reset.
+func TestCShakeSamples(t *testing.T) {
+	for _, sample := range cShakeSamples {
+		d := sample.newCShake([]byte(sample.N), []byte(sample.S))
+		for _, pass := range []string{"new", "reset"} {
+			d.Write(sequentialBytes(sample.dataLen))
+			out := make([]byte, len(sample.digest)/2)
+			d.Read(out)
+			if got := strings.ToUpper(hex.EncodeToString(out)); got != sample.digest {
+				t.Errorf("%s(%d bytes), %s: got %s, want %s",
+					sample.name, sample.dataLen, pass, got, sample.digest)
+			}
+			d.Reset()
+		}
+	}
+}
+
+// TestCShakeClone checks that a clone of a cSHAKE instance continues
+// independently of the original.
+func TestCShakeClone(t *testing.T) {
+	d0 := NewCShake256([]byte("N"), []byte("S"))
+	d0.Write([]byte(testString))
+	d1 := d0.Clone()
+	d0.Write([]byte(testString))
+	d1.Write([]byte(testString))
+	ref := make([]byte, 64)
+	d0.Read(ref)
+	out := make([]byte, 64)
+	d1.Read(out)
+	if !bytes.Equal(ref, out) {
+		t.Errorf("clone diverged from original:\ngot  %x\nwant %x", out, ref)
+	}
+}
+
+// TestCloneSqueezing checks that a clone of a SHAKE instance taken in
+// the middle of squeezing continues from the same output position.
+func TestCloneSqueezing(t *testing.T) {
+	for name, newShake := range map[string]func() ShakeHash{
+		"SHAKE128": NewShake128,
+		"SHAKE256": NewShake256,
+	} {
+		for _, read := range []int{1, 100, 200, 400} {
+			d0 := newShake()
+			d0.Write([]byte(testString))
+			d0.Read(make([]byte, read))
+			d1 := d0.Clone()
+			ref := make([]byte, 300)
+			d0.Read(ref)
+			out := make([]byte, 300)
+			d1.Read(out)
+			if !bytes.Equal(ref, out) {
+				t.Errorf("%s, cloned after %d bytes:\ngot  %x\nwant %x", name, read, out, ref)
+			}
+		}
+	}
+}
+
+// TestCShakeNoCustomization checks that cSHAKE without N or S is SHAKE.
+func TestCShakeNoCustomization(t *testing.T) {
+	for _, v := range []struct {
+		newShake  func() ShakeHash
+		newCShake func(N, S []byte) ShakeHash
+	}{
+		{NewShake128, NewCShake128},
+		{NewShake256, NewCShake256},
+	} {
+		want := make([]byte, 64)
+		d := v.newShake()
+		d.Write([]byte(testString))
+		d.Read(want)
+		got := make([]byte, 64)
+		d = v.newCShake(nil, []byte{})
+		d.Write([]byte(testString))
+		d.Read(got)
+		if !bytes.Equal(got, want) {
+			t.Errorf("got %x, want %x", got, want)
+		}
+	}
+}
+
+// kmacSamples are the KMAC and KMACXOF sample values published by NIST
+// for SP 800-185. All of them use the key 0x40, 0x41, ..., 0x5F.
+var kmacSamples = []struct {
+	name    string
+	bits    int
+	xof     bool
+	dataLen int
+	S       string
+	mac     string
+}{
+	{"KMAC128", 128, false, 4, "",
+		"E5780B0D3EA6F7D3A429C5706AA43A00FADBD7D49628839E3187243F456EE14E"},
+	{"KMAC128", 128, false, 4, "My Tagged Application",
+		"3B1FBA963CD8B0B59E8C1A6D71888B7143651AF8BA0A7070C0979E2811324AA5"},
+	{"KMAC128", 128, false, 200, "My Tagged Application",
+		"1F5B4E6CCA02209E0DCB5CA635B89A15E271ECC760071DFD805FAA38F9729230"},
+	{"KMAC256", 256, false, 4, "My Tagged Application",
+		"20C570C31346F703C9AC36C61C03CB64C3970D0CFC787E9B79599D273A68D2F7" +
+			"F69D4CC3DE9D104A351689F27CF6F5951F0103F33F4F24871024D9C27773A8DD"},
+	{"KMAC256", 256, false, 200, "",
+		"75358CF39E41494E949707927CEE0AF20A3FF553904C86B08F21CC414BCFD691" +
+			"589D27CF5E15369CBBFF8B9A4C2EB17800855D0235FF635DA82533EC6B759B69"},
+	{"KMAC256", 256, false, 200, "My Tagged Application",
+		"B58618F71F92E1D56C1B8C55DDD7CD188B97B4CA4D99831EB2699A837DA2E4D9" +
+			"70FBACFDE50033AEA585F1A2708510C32D07880801BD182898FE476876FC8965"},
+	{"KMACXOF128", 128, true, 4, "",
+		"CD83740BBD92CCC8CF032B1481A0F4460E7CA9DD12B08A0C4031178BACD6EC35"},
+	{"KMACXOF128", 128, true, 4, "My Tagged Application",
+		"31A44527B4ED9F5C6101D11DE6D26F0620AA5C341DEF41299657FE9DF1A3B16C"},
+	{"KMACXOF128", 128, true, 200, "My Tagged Application",
+		"47026C7CD793084AA0283C253EF658490C0DB61438B8326FE9BDDF281B83AE0F"},
+	{"KMACXOF256", 256, true, 4, "My Tagged Application",
+		"1755133F1534752AAD0748F2C706FB5C784512CAB835CD15676B16C0C6647FA9" +
+			"6FAA7AF634A0BF8FF6DF39374FA00FAD9A39E322A7C92065A64EB1FB0801EB2B"},
+	{"KMACXOF256", 256, true, 200, "",
+		"FF7B171F1E8A2B24683EED37830EE797538BA8DC563F6DA1E667391A75EDC02C" +
+			"A633079F81CE12A25F45615EC89972031D18337331D24CEB8F8CA8E6A19FD98B"},
+	{"KMACXOF256", 256, true, 200, "My Tagged Application",
+		"D5BE731C954ED7732846BB59DBE3A8E30F83E77A4BFF4459F2F1C2B4ECEBB8CE" +
+			"67BA01C62E8AB8578D2D499BD1BB276768781190020A306A97DE281DCC30305D"},
+}
+
+// TestKMACSamples tests KMAC and KMACXOF against the NIST samples, both on
+// a fresh instance and after Reset.
+func TestKMACSamples(t *testing.T) {
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(0x40 + i)
+	}
+	for _, sample := range kmacSamples {
+		outputLen := len(sample.mac) / 2
+		S := []byte(sample.S)
+		var out func() []byte
+		var reset func()
+		var d io.Writer
+		switch {
+		case sample.xof:
+			x := NewKMACXOF128(key, S)
+			if sample.bits == 256 {
+				x = NewKMACXOF256(key, S)
+			}
+			d, reset = x, x.Reset
+			out = func() []byte {
+				b := make([]byte, outputLen)
+				x.Read(b)
+				return b
+			}
+		default:
+			h := NewKMAC128(key, outputLen, S)
+			if sample.bits == 256 {
+				h = NewKMAC256(key, outputLen, S)
+			}
+			d, reset = h, h.Reset
+			out = func() []byte { return h.Sum(nil) }
+		}
+		for _, pass := range []string{"new", "reset"} {
+			d.Write(sequentialBytes(sample.dataLen))
+			if got := strings.ToUpper(hex.EncodeToString(out())); got != sample.mac {
+				t.Errorf("%s(%d bytes, S=%q), %s: got %s, want %s",
+					sample.name, sample.dataLen, sample.S, pass, got, sample.mac)
+			}
+			reset()
+		}
+	}
+}
+
+// TestKMACOutputLength checks that the requested output length is part of
+// the KMAC input, so that a shorter MAC is not a prefix of a longer one.
+func TestKMACOutputLength(t *testing.T) {
+	key := []byte("a key of at least thirty-two bytes")
+	short := NewKMAC256(key, 32, nil)
+	long := NewKMAC256(key, 64, nil)
+	short.Write([]byte(testString))
+	long.Write([]byte(testString))
+	if s, l := short.Sum(nil), long.Sum(nil); bytes.Equal(s, l[:len(s)]) {
+		t.Errorf("32-byte KMAC256 is a prefix of 64-byte KMAC256: %x", s)
+	}
+	if short.Size() != 32 || long.Size() != 64 {
+		t.Errorf("got sizes %d and %d, want 32 and 64", short.Size(), long.Size())
+	}
+}
+
+// TestKMACNotXOF checks that the fixed-output-length KMAC can not be read
+// from as a KMACXOF.
+func TestKMACNotXOF(t *testing.T) {
+	for _, h := range []hash.Hash{NewKMAC128(nil, 32, nil), NewKMAC256(nil, 64, nil)} {
+		if _, ok := h.(io.Reader); ok {
+			t.Errorf("%T is an io.Reader", h)
+		}
+		if _, ok := h.(ShakeHash); ok {
+			t.Errorf("%T is a ShakeHash", h)
+		}
+	}
+}
+
+// tupleHashSamples are the TupleHash and TupleHashXOF sample values
+// published by NIST for SP 800-185. The tuples consist of the first two
+// or three of the elements 0x00..0x02, 0x10..0x15 and 0x20..0x28.
+var tupleHashSamples = []struct {
+	name   string
+	bits   int
+	xof    bool
+	n      int
+	S      string
+	digest string
+}{
+	{"TupleHash128", 128, false, 2, "",
+		"C5D8786C1AFB9B82111AB34B65B2C0048FA64E6D48E263264CE1707D3FFC8ED1"},
+	{"TupleHash128", 128, false, 2, "My Tuple App",
+		"75CDB20FF4DB1154E841D758E24160C54BAE86EB8C13E7F5F40EB35588E96DFB"},
+	{"TupleHash128", 128, false, 3, "My Tuple App",
+		"E60F202C89A2631EDA8D4C588CA5FD07F39E5151998DECCF973ADB3804BB6E84"},
+	{"TupleHash256", 256, false, 2, "",
+		"CFB7058CACA5E668F81A12A20A2195CE97A925F1DBA3E7449A56F82201EC6073" +
+			"11AC2696B1AB5EA2352DF1423BDE7BD4BB78C9AED1A853C78672F9EB23BBE194"},
+	{"TupleHash256", 256, false, 2, "My Tuple App",
+		"147C2191D5ED7EFD98DBD96D7AB5A11692576F5FE2A5065F3E33DE6BBA9F3AA1" +
+			"C4E9A068A289C61C95AAB30AEE1E410B0B607DE3620E24A4E3BF9852A1D4367E"},
+	{"TupleHash256", 256, false, 3, "My Tuple App",
+		"45000BE63F9B6BFD89F54717670F69A9BC763591A4F05C50D68891A744BCC6E7" +
+			"D6D5B5E82C018DA999ED35B0BB49C9678E526ABD8E85C13ED254021DB9E790CE"},
+	{"TupleHashXOF128", 128, true, 2, "",
+		"2F103CD7C32320353495C68DE1A8129245C6325F6F2A3D608D92179C96E68488"},
+	{"TupleHashXOF128", 128, true, 2, "My Tuple App",
+		"3FC8AD69453128292859A18B6C67D7AD85F01B32815E22CE839C49EC374E9B9A"},
+	{"TupleHashXOF128", 128, true, 3, "My Tuple App",
+		"900FE16CAD098D28E74D632ED852F99DAAB7F7DF4D99E775657885B4BF76D6F8"},
+	{"TupleHashXOF256", 256, true, 2, "",
+		"03DED4610ED6450A1E3F8BC44951D14FBC384AB0EFE57B000DF6B6DF5AAE7CD5" +
+			"68E77377DAF13F37EC75CF5FC598B6841D51DD207C991CD45D210BA60AC52EB9"},
+	{"TupleHashXOF256", 256, true, 2, "My Tuple App",
+		"6483CB3C9952EB20E830AF4785851FC597EE3BF93BB7602C0EF6A65D741AECA7" +
+			"E63C3B128981AA05C6D27438C79D2754BB1B7191F125D6620FCA12CE658B2442"},
+	{"TupleHashXOF256", 256, true, 3, "My Tuple App",
+		"0C59B11464F2336C34663ED51B2B950BEC743610856F36C28D1D088D8A244628" +
+			"4DD09830A6A178DC752376199FAE935D86CFDEE5913D4922DFD369B66A53C897"},
+}
+
+// tupleSampleElements returns the first n elements of the tuples used in
+// the NIST TupleHash samples.
+func tupleSampleElements(n int) [][]byte {
+	var tuple [][]byte
+	for i, size := range []int{3, 6, 9}[:n] {
+		e := sequentialBytes(size)
+		for j := range e {
+			e[j] += byte(0x10 * i)
+		}
+		tuple = append(tuple, e)
+	}
+	return tuple
+}
+
+// TestTupleHashSamples tests TupleHash and TupleHashXOF against the NIST
+// samples, writing each element whole and then streaming it a byte at a
+// time after Reset.
+func TestTupleHashSamples(t *testing.T) {
+	for _, sample := range tupleHashSamples {
+		outputLen := len(sample.digest) / 2
+		S := []byte(sample.S)
+		var d *TupleHash
+		switch {
+		case sample.xof && sample.bits == 128:
+			d = NewTupleHashXOF128(S)
+		case sample.xof:
+			d = NewTupleHashXOF256(S)
+		case sample.bits == 128:
+			d = NewTupleHash128(outputLen, S)
+		default:
+			d = NewTupleHash256(outputLen, S)
+		}
+		tuple := tupleSampleElements(sample.n)
+		for _, pass := range []string{"whole", "streamed"} {
+			for _, e := range tuple {
+				if pass == "whole" {
+					d.WriteElement(e)
+					continue
+				}
+				d.StartElement(int64(len(e)))
+				for i := range e {
+					d.Write(e[i : i+1])
+				}
+			}
+			out := make([]byte, outputLen)
+			if sample.xof {
+				d.Read(out)
+			} else {
+				out = d.Sum(nil)
+			}
+			if got := strings.ToUpper(hex.EncodeToString(out)); got != sample.digest {
+				t.Errorf("%s(%d elements, S=%q), %s: got %s, want %s",
+					sample.name, sample.n, sample.S, pass, got, sample.digest)
+			}
+			d.Reset()
+		}
+	}
+}
+
+// TestTupleHashUnambiguous checks that moving bytes between elements, and
+// the element overflow check, both behave.
+func TestTupleHashUnambiguous(t *testing.T) {
+	a := TupleHash256([][]byte{[]byte("ab"), []byte("c")}, 64, nil)
+	b := TupleHash256([][]byte{[]byte("a"), []byte("bc")}, 64, nil)
+	if bytes.Equal(a, b) {
+		t.Errorf("(ab, c) and (a, bc) have the same TupleHash256")
+	}
+
+	d := NewTupleHash256(64, nil)
+	d.StartElement(2)
+	if n, err := d.Write([]byte("abc")); n != 2 || err == nil {
+		t.Errorf("overlong element write: got (%d, %v), want (2, error)", n, err)
+	}
+	d.WriteElement([]byte("c"))
+	if got := d.Sum(nil); !bytes.Equal(got, a) {
+		t.Errorf("got %x after overflow, want %x", got, a)
+	}
+
+	// An element of 2**61 bytes or more would have its length in bits
+	// wrap around, and be encoded as a shorter one.
+	for _, size := range []int64{-1, 1 << 61, 1<<63 - 1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("StartElement(%d) did not panic", size)
+				}
+			}()
+			NewTupleHash256(64, nil).StartElement(size)
+		}()
+	}
+}
+
+// parallelHashSamples are the ParallelHash and ParallelHashXOF sample
+// values published by NIST for SP 800-185. The input consists of groups
+// of blockSize consecutive bytes starting at 0x00, 0x10, 0x20, ...
+var parallelHashSamples = []struct {
+	name      string
+	bits      int
+	xof       bool
+	blockSize int
+	groups    int
+	S         string
+	digest    string
+}{
+	{"ParallelHash128", 128, false, 8, 3, "",
+		"BA8DC1D1D979331D3F813603C67F72609AB5E44B94A0B8F9AF46514454A2B4F5"},
+	{"ParallelHash128", 128, false, 8, 3, "Parallel Data",
+		"FC484DCB3F84DCEEDC353438151BEE58157D6EFED0445A81F165E495795B7206"},
+	{"ParallelHash128", 128, false, 12, 6, "Parallel Data",
+		"F7FD5312896C6685C828AF7E2ADB97E393E7F8D54E3C2EA4B95E5ACA3796E8FC"},
+	{"ParallelHash256", 256, false, 8, 3, "",
+		"BC1EF124DA34495E948EAD207DD9842235DA432D2BBC54B4C110E64C45110553" +
+			"1B7F2A3E0CE055C02805E7C2DE1FB746AF97A1DD01F43B824E31B87612410429"},
+	{"ParallelHash256", 256, false, 8, 3, "Parallel Data",
+		"CDF15289B54F6212B4BC270528B49526006DD9B54E2B6ADD1EF6900DDA3963BB" +
+			"33A72491F236969CA8AFAEA29C682D47A393C065B38E29FAE651A2091C833110"},
+	{"ParallelHash256", 256, false, 12, 6, "Parallel Data",
+		"69D0FCB764EA055DD09334BC6021CB7E4B61348DFF375DA262671CDEC3EFFA8D" +
+			"1B4568A6CCE16B1CAD946DDDE27F6CE2B8DEE4CD1B24851EBF00EB90D43813E9"},
+	{"ParallelHashXOF128", 128, true, 8, 3, "",
+		"FE47D661E49FFE5B7D999922C062356750CAF552985B8E8CE6667F2727C3C8D3"},
+	{"ParallelHashXOF128", 128, true, 8, 3, "Parallel Data",
+		"EA2A793140820F7A128B8EB70A9439F93257C6E6E79B4A540D291D6DAE7098D7"},
+	{"ParallelHashXOF128", 128, true, 12, 6, "Parallel Data",
+		"0127AD9772AB904691987FCC4A24888F341FA0DB2145E872D4EFD255376602F0"},
+	{"ParallelHashXOF256", 256, true, 8, 3, "",
+		"C10A052722614684144D28474850B410757E3CBA87651BA167A5CBDDFF7F4666" +
+			"75FBF84BCAE7378AC444BE681D729499AFCA667FB879348BFDDA427863C82F1C"},
+	{"ParallelHashXOF256", 256, true, 8, 3, "Parallel Data",
+		"538E105F1A22F44ED2F5CC1674FBD40BE803D9C99BF5F8D90A2C8193F3FE6EA7" +
+			"68E5C1A20987E2C9C65FEBED03887A51D35624ED12377594B5585541DC377EFC"},
+	{"ParallelHashXOF256", 256, true, 12, 6, "Parallel Data",
+		"6B3E790B330C889A204C2FBC728D809F19367328D852F4002DC829F73AFD6BCE" +
+			"FB7FE5B607B13A801C0BE5C1170BDB794E339458FDB0E62A6AF3D42558970249"},
+}
+
+// newParallelHashForTest returns the ParallelHash instance described by
+// bits and xof, producing outputLen bytes of output.
+func newParallelHashForTest(bits int, xof bool, blockSize, outputLen int, S []byte) *ParallelHash {
+	switch {
+	case xof && bits == 128:
+		return NewParallelHashXOF128(blockSize, S)
+	case xof:
+		return NewParallelHashXOF256(blockSize, S)
+	case bits == 128:
+		return NewParallelHash128(blockSize, outputLen, S)
+	}
+	return NewParallelHash256(blockSize, outputLen, S)
+}
+
+// parallelHashOutput returns outputLen bytes of output from p.
+func parallelHashOutput(p *ParallelHash, xof bool, outputLen int) []byte {
+	if !xof {
+		return p.Sum(nil)
+	}
+	out := make([]byte, outputLen)
+	p.Read(out)
+	return out
+}
+
+// TestParallelHashSamples tests ParallelHash and ParallelHashXOF against
+// the NIST samples, with a single worker and with several.
+func TestParallelHashSamples(t *testing.T) {
+	for _, sample := range parallelHashSamples {
+		var data []byte
+		for i := 0; i < sample.groups; i++ {
+			group := sequentialBytes(sample.blockSize)
+			for j := range group {
+				group[j] += byte(0x10 * i)
+			}
+			data = append(data, group...)
+		}
+		outputLen := len(sample.digest) / 2
+		for _, workers := range []int{1, 4} {
+			p := newParallelHashForTest(sample.bits, sample.xof, sample.blockSize, outputLen, []byte(sample.S))
+			p.SetWorkers(workers)
+			p.Write(data)
+			out := parallelHashOutput(p, sample.xof, outputLen)
+			if got := strings.ToUpper(hex.EncodeToString(out)); got != sample.digest {
+				t.Errorf("%s(B=%d, S=%q), %d workers: got %s, want %s",
+					sample.name, sample.blockSize, sample.S, workers, got, sample.digest)
+			}
+		}
+	}
+}
+
+// referenceParallelHash computes ParallelHash sequentially, directly from
+// the definition in section 6.3 of SP 800-185.
+func referenceParallelHash(bits int, xof bool, data []byte, blockSize, outputLen int, S []byte) []byte {
+	newLeaf, newOuter, cvLen := NewShake128, NewCShake128, 32
+	if bits == 256 {
+		newLeaf, newOuter, cvLen = NewShake256, NewCShake256, 64
+	}
+	z := leftEncode(uint64(blockSize))
+	n := 0
+	for ; len(data) > 0; n++ {
+		block := data
+		if len(block) > blockSize {
+			block = block[:blockSize]
+		}
+		data = data[len(block):]
+		leaf := newLeaf()
+		leaf.Write(block)
+		cv := make([]byte, cvLen)
+		leaf.Read(cv)
+		z = append(z, cv...)
+	}
+	z = append(z, rightEncode(uint64(n))...)
+	if xof {
+		z = append(z, rightEncode(0)...)
+	} else {
+		z = append(z, rightEncode(uint64(outputLen)*8)...)
+	}
+	outer := newOuter([]byte("ParallelHash"), S)
+	outer.Write(z)
+	out := make([]byte, outputLen)
+	outer.Read(out)
+	return out
+}
+
+// TestParallelHashReference checks that ParallelHash matches a sequential
+// implementation for inputs around block boundaries, different worker
+// counts and irregular writes.
+func TestParallelHashReference(t *testing.T) {
+	const blockSize = 37
+	S := []byte("reference")
+	for _, bits := range []int{128, 256} {
+		for _, xof := range []bool{false, true} {
+			for _, size := range []int{0, 1, blockSize - 1, blockSize, blockSize + 1, 10 * blockSize, 1000*blockSize + 5} {
+				data := sequentialBytes(size)
+				want := referenceParallelHash(bits, xof, data, blockSize, 48, S)
+				for _, workers := range []int{1, 3, 8} {
+					p := newParallelHashForTest(bits, xof, blockSize, 48, S)
+					p.SetWorkers(workers)
+					for i, j := 0, 1; i < len(data); j = j*2 + 1 {
+						if j > len(data)-i {
+							j = len(data) - i
+						}
+						p.Write(data[i : i+j])
+						i += j
+					}
+					if !xof {
+						// Sum must not disturb the state.
+						p.Sum(nil)
+					}
+					if got := parallelHashOutput(p, xof, 48); !bytes.Equal(got, want) {
+						t.Errorf("ParallelHash%d (xof=%v), %d bytes, %d workers:\ngot  %x\nwant %x",
+							bits, xof, size, workers, got, want)
+					}
+				}
+			}
+		}
+	}
+}
+
+// TestLegacyKeccak tests the legacy Keccak hash functions, which use the
+// padding of the original Keccak submission, against known answers.
+func TestLegacyKeccak(t *testing.T) {
+	const fox = "The quick brown fox jumps over the lazy dog"
+	tests := []struct {
+		name   string
+		newH   func() hash.Hash
+		sum    func([]byte) []byte
+		data   string
+		digest string
+	}{
+		{"Keccak-256", NewLegacyKeccak256,
+			func(b []byte) []byte { d := LegacyKeccakSum256(b); return d[:] }, "",
+			"c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+		{"Keccak-256", NewLegacyKeccak256,
+			func(b []byte) []byte { d := LegacyKeccakSum256(b); return d[:] }, "abc",
+			"4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45"},
+		{"Keccak-256", NewLegacyKeccak256,
+			func(b []byte) []byte { d := LegacyKeccakSum256(b); return d[:] }, fox,
+			"4d741b6f1eb29cb2a9b9911c82f56fa8d73b04959d3d9d222895df6c0b28aa15"},
+		{"Keccak-512", NewLegacyKeccak512,
+			func(b []byte) []byte { d := LegacyKeccakSum512(b); return d[:] }, "",
+			"0eab42de4c3ceb9235fc91acffe746b29c29a8c366b7c60e4e67c466f36a4304" +
+				"c00fa9caf9d87976ba469bcbe06713b435f091ef2769fb160cdab33d3670680e"},
+		{"Keccak-512", NewLegacyKeccak512,
+			func(b []byte) []byte { d := LegacyKeccakSum512(b); return d[:] }, "abc",
+			"18587dc2ea106b9a1563e32b3312421ca164c7f1f07bc922a9c83d77cea3a1e5" +
+				"d0c69910739025372dc14ac9642629379540c17e2a65b19d77aa511a9d00bb96"},
+		{"Keccak-512", NewLegacyKeccak512,
+			func(b []byte) []byte { d := LegacyKeccakSum512(b); return d[:] }, fox,
+			"d135bb84d0439dbac432247ee573a23ea7d3c9deb2a968eb31d47c4fb45f1ef4" +
+				"422d6c531b5b9bd6f449ebcc449ea94d0a8f05f62130fda612da53c79659f609"},
+	}
+	for _, test := range tests {
+		h := test.newH()
+		h.Write([]byte(test.data))
+		if got := hex.EncodeToString(h.Sum(nil)); got != test.digest {
+			t.Errorf("%s(%q): got %s, want %s", test.name, test.data, got, test.digest)
+		}
+		if got := hex.EncodeToString(test.sum([]byte(test.data))); got != test.digest {
+			t.Errorf("%s sum helper(%q): got %s, want %s", test.name, test.data, got, test.digest)
+		}
+	}
+}
+
 func TestReadSimulation(t *testing.T) {
 	d := NewShake256()
 	d.Write(nil)
@@ -242,8 +843,12 @@ func BenchmarkSha3_512_MTU(b *testing.B) { benchmarkBulkHash(b, New512(), 1350)
 func BenchmarkSha3_384_MTU(b *testing.B) { benchmarkBulkHash(b, New384(), 1350) }
 func BenchmarkSha3_256_MTU(b *testing.B) { benchmarkBulkHash(b, New256(), 1350) }
 func BenchmarkSha3_224_MTU(b *testing.B) { benchmarkBulkHash(b, New224(), 1350) }
-func BenchmarkShake256_MTU(b *testing.B) { benchmarkBulkHash(b, newHashShake256(), 1350) }
-func BenchmarkShake128_MTU(b *testing.B) { benchmarkBulkHash(b, newHashShake128(), 1350) }
+func BenchmarkShake256_MTU(b *testing.B) { benchmarkBulkHash(b, NewShake256Hash(512), 1350) }
+func BenchmarkShake128_MTU(b *testing.B) { benchmarkBulkHash(b, NewShake128Hash(512), 1350) }
 
 func BenchmarkSha3_512_1MiB(b *testing.B) { benchmarkBulkHash(b, New512(), 1<<20) }
-func BenchmarkShake256_1MiB(b *testing.B) { benchmarkBulkHash(b, newHashShake256(), 1<<20) }
+func BenchmarkShake256_1MiB(b *testing.B) { benchmarkBulkHash(b, NewShake256Hash(512), 1<<20) }
+
+func BenchmarkParallelHash256_1MiB(b *testing.B) {
+	benchmarkBulkHash(b, NewParallelHash256(8192, 64, nil), 1<<20)
+}
@@ -5,10 +5,15 @@
 package sha3
 
 // This file defines the ShakeHash interface, and provides
-// functions for creating SHAKE instances, as well as utility
+// functions for creating SHAKE and cSHAKE instances, as well as utility
 // functions for hashing bytes to arbitrary-length output.
+//
+// cSHAKE is specified in NIST SP 800-185, "SHA-3 Derived Functions":
+// http://dx.doi.org/10.6028/NIST.SP.800-185
 
 import (
+	"encoding/binary"
+	"hash"
 	"io"
 )
 
@@ -31,6 +36,77 @@ type ShakeHash interface {
 	Reset()
 }
 
+// cshakeState is a cSHAKE instance: a SHAKE sponge that has absorbed
+// the encoded function-name and customization strings.
+type cshakeState struct {
+	*state // SHA-3 sponge and its Read and Write operations
+
+	// initBlock is the encoding of N and S described in section 3.3 of
+	// SP 800-185, before bytepad is applied. It is kept so that Reset can
+	// return the sponge to its customized initial state.
+	initBlock []byte
+}
+
+const (
+	dsbyteShake  = 0x1f
+	dsbyteCShake = 0x04
+	rate128      = 168
+	rate256      = 136
+)
+
+// leftEncode returns the encoding of x as defined by left_encode in
+// section 2.3.1 of SP 800-185: the big-endian bytes of x, with leading
+// zeros removed, preceded by their count.
+func leftEncode(x uint64) []byte {
+	var b [9]byte
+	binary.BigEndian.PutUint64(b[1:], x)
+	// Strip all but the last leading zero byte.
+	i := 1
+	for i < 8 && b[i] == 0 {
+		i++
+	}
+	b[i-1] = byte(9 - i)
+	return b[i-1:]
+}
+
+// encodeString returns the encoding of s as defined by encode_string in
+// section 2.3.2 of SP 800-185: its length in bits, left_encoded, followed
+// by s itself.
+func encodeString(s []byte) []byte {
+	return append(leftEncode(uint64(len(s))*8), s...)
+}
+
+// bytepad returns in prefixed with left_encode(w) and padded with zeros
+// to a multiple of w bytes, as defined in section 2.3.3 of SP 800-185.
+func bytepad(in []byte, w int) []byte {
+	buf := make([]byte, 0, 9+len(in)+w)
+	buf = append(buf, leftEncode(uint64(w))...)
+	buf = append(buf, in...)
+	padlen := w - len(buf)%w
+	if padlen == w {
+		padlen = 0
+	}
+	return append(buf, make([]byte, padlen)...)
+}
+
+func newCShake(N, S []byte, rate int) *cshakeState {
+	c := &cshakeState{state: &state{rate: rate, dsbyte: dsbyteCShake}}
+	c.initBlock = append(encodeString(N), encodeString(S)...)
+	c.Write(bytepad(c.initBlock, c.rate))
+	return c
+}
+
+// Reset resets the cSHAKE instance to its customized initial state.
+func (c *cshakeState) Reset() {
+	c.state.Reset()
+	c.Write(bytepad(c.initBlock, c.rate))
+}
+
+// Clone returns a copy of the cSHAKE instance in its current state.
+func (c *cshakeState) Clone() ShakeHash {
+	return &cshakeState{state: c.clone(), initBlock: c.initBlock}
+}
+
 func (d *state) Clone() ShakeHash {
 	return d.clone()
 }
@@ -38,12 +114,83 @@ func (d *state) Clone() ShakeHash {
 // NewShake128 creates a new SHAKE128 variable-output-length ShakeHash.
 // Its generic security strength is 128 bits against all attacks if at
 // least 32 bytes of its output are used.
-func NewShake128() ShakeHash { return &state{rate: 168, dsbyte: 0x1f} }
+func NewShake128() ShakeHash { return &state{rate: rate128, dsbyte: dsbyteShake} }
 
 // NewShake256 creates a new SHAKE128 variable-output-length ShakeHash.
 // Its generic security strength is 256 bits against all attacks if
 // at least 64 bytes of its output are used.
-func NewShake256() ShakeHash { return &state{rate: 136, dsbyte: 0x1f} }
+func NewShake256() ShakeHash { return &state{rate: rate256, dsbyte: dsbyteShake} }
+
+// fixedShake is a SHAKE instance with a fixed output length, returned by
+// NewShake128Hash and NewShake256Hash. It has no Read method, so that it
+// can not be mistaken for a ShakeHash.
+type fixedShake struct {
+	d *state
+}
+
+// BlockSize returns the rate of the sponge underlying SHAKE.
+func (f *fixedShake) BlockSize() int { return f.d.rate }
+
+// Size returns the output length in bytes.
+func (f *fixedShake) Size() int { return f.d.outputLen }
+
+// Write absorbs more data into the hash's state.
+func (f *fixedShake) Write(p []byte) (int, error) { return f.d.Write(p) }
+
+// Reset clears the internal state.
+func (f *fixedShake) Reset() { f.d.Reset() }
+
+// Sum squeezes Size bytes of output from a copy of the state, so that the
+// caller can keep writing and summing.
+func (f *fixedShake) Sum(in []byte) []byte { return f.d.Sum(in) }
+
+// NewShake128Hash creates a new hash.Hash computing SHAKE128 with an
+// output of n bytes, for use where a hash.Hash is needed: Size returns n,
+// and Sum appends n bytes of output without changing the state. It panics
+// if n is not positive.
+func NewShake128Hash(n int) hash.Hash {
+	if n <= 0 {
+		panic("sha3: non-positive SHAKE output length")
+	}
+	return &fixedShake{&state{rate: rate128, dsbyte: dsbyteShake, outputLen: n}}
+}
+
+// NewShake256Hash creates a new hash.Hash computing SHAKE256 with an
+// output of n bytes, for use where a hash.Hash is needed: Size returns n,
+// and Sum appends n bytes of output without changing the state. It panics
+// if n is not positive.
+func NewShake256Hash(n int) hash.Hash {
+	if n <= 0 {
+		panic("sha3: non-positive SHAKE output length")
+	}
+	return &fixedShake{&state{rate: rate256, dsbyte: dsbyteShake, outputLen: n}}
+}
+
+// NewCShake128 creates a new cSHAKE128 variable-output-length ShakeHash,
+// the customizable variant of SHAKE128 defined in SP 800-185. N is the
+// function-name string, reserved for functions defined by NIST, and S is
+// a customization string: two cSHAKE128 instances with different N or S
+// produce unrelated output for the same input. When N and S are both
+// empty, it is equivalent to NewShake128.
+func NewCShake128(N, S []byte) ShakeHash {
+	if len(N) == 0 && len(S) == 0 {
+		return NewShake128()
+	}
+	return newCShake(N, S, rate128)
+}
+
+// NewCShake256 creates a new cSHAKE256 variable-output-length ShakeHash,
+// the customizable variant of SHAKE256 defined in SP 800-185. N is the
+// function-name string, reserved for functions defined by NIST, and S is
+// a customization string: two cSHAKE256 instances with different N or S
+// produce unrelated output for the same input. When N and S are both
+// empty, it is equivalent to NewShake256.
+func NewCShake256(N, S []byte) ShakeHash {
+	if len(N) == 0 && len(S) == 0 {
+		return NewShake256()
+	}
+	return newCShake(N, S, rate256)
+}
 
 // ShakeSum128 writes an arbitrary-length digest of data into hash.
 func ShakeSum128(hash, data []byte) {
@@ -58,3 +205,17 @@ func ShakeSum256(hash, data []byte) {
 	h.Write(data)
 	h.Read(hash)
 }
+
+// ShakeSum128N returns length bytes of the SHAKE128 digest of data.
+func ShakeSum128N(data []byte, length int) []byte {
+	out := make([]byte, length)
+	ShakeSum128(out, data)
+	return out
+}
+
+// ShakeSum256N returns length bytes of the SHAKE256 digest of data.
+func ShakeSum256N(data []byte, length int) []byte {
+	out := make([]byte, length)
+	ShakeSum256(out, data)
+	return out
+}
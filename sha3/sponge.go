@@ -0,0 +1,103 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+// This file exports the sponge construction underlying the functions of
+// this package, with its parameters chosen by the caller, so that other
+// sponge functions can be built on it.
+
+import (
+	"errors"
+)
+
+var (
+	errSpongeRate   = errors.New("sha3: sponge rate must be between 1 and 168 bytes")
+	errSpongeDS     = errors.New("sha3: sponge domain separation byte must be between 0x01 and 0x7F")
+	errSpongeRounds = errors.New("sha3: sponge must use between 1 and 24 rounds")
+)
+
+// A Sponge is a sponge function on the Keccak-p[1600, rounds] permutation
+// with a rate and domain separation bits chosen by the caller. Input is
+// absorbed with Absorb; Pad then appends the domain separation bits and
+// the multi-rate padding, after which output is read with Squeeze.
+//
+// NewSponge(136, 0x1f, 24), for instance, is SHAKE256, and
+// NewSponge(136, 0x06, 24) is SHA3-256 when 32 bytes are squeezed.
+type Sponge struct {
+	s *state
+}
+
+// NewSponge returns a new Sponge that absorbs and squeezes rate bytes per
+// call to the permutation; its capacity is 200-rate bytes. rate must be
+// at most 168 bytes, so that the capacity is at least 256 bits. dsbyte
+// holds the domain separation bits followed by the first one bit of the
+// padding, numbered from the least significant bit up: 0x06 for SHA-3 and
+// 0x1F for SHAKE. It must be between 0x01 and 0x7F. rounds is the number
+// of rounds of the permutation, from 1 to 24; Keccak-f[1600] has 24.
+func NewSponge(rate int, dsbyte byte, rounds int) (*Sponge, error) {
+	s, err := newSpongeState(rate, dsbyte, rounds)
+	if err != nil {
+		return nil, err
+	}
+	return &Sponge{s: s}, nil
+}
+
+// newSpongeState validates the parameters of NewSponge and returns a
+// state using them.
+func newSpongeState(rate int, dsbyte byte, rounds int) (*state, error) {
+	if rate < 1 || rate > maxRate {
+		return nil, errSpongeRate
+	}
+	if dsbyte < 0x01 || dsbyte > 0x7f {
+		return nil, errSpongeDS
+	}
+	if rounds < 1 || rounds > len(rc) {
+		return nil, errSpongeRounds
+	}
+	s := &state{rate: rate, dsbyte: dsbyte}
+	if rounds != len(rc) {
+		s.rounds = rounds
+	}
+	return s, nil
+}
+
+// Rate returns the number of bytes absorbed or squeezed per call to the
+// permutation.
+func (sp *Sponge) Rate() int { return sp.s.rate }
+
+// Capacity returns the number of bytes of the state that are never
+// directly absorbed into or squeezed from.
+func (sp *Sponge) Capacity() int { return 200 - sp.s.rate }
+
+// Absorb absorbs p into the sponge. It panics if the sponge has already
+// been padded.
+func (sp *Sponge) Absorb(p []byte) {
+	if sp.s.state != spongeAbsorbing {
+		panic("sha3: Absorb called after Pad")
+	}
+	sp.s.Write(p)
+}
+
+// Pad appends the domain separation bits and the padding to the absorbed
+// input and switches the sponge to squeezing. It panics if the sponge has
+// already been padded.
+func (sp *Sponge) Pad() {
+	if sp.s.state != spongeAbsorbing {
+		panic("sha3: Pad called twice")
+	}
+	sp.s.padAndPermute(sp.s.dsbyte)
+}
+
+// Squeeze fills out with the next len(out) bytes of the sponge's output.
+// The sponge is padded first if Pad has not been called.
+func (sp *Sponge) Squeeze(out []byte) {
+	sp.s.Read(out)
+}
+
+// Reset returns the sponge to its initial, empty state.
+func (sp *Sponge) Reset() { sp.s.Reset() }
+
+// Clone returns a copy of the Sponge in its current state.
+func (sp *Sponge) Clone() *Sponge { return &Sponge{s: sp.s.clone()} }
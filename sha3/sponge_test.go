@@ -0,0 +1,112 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestSpongeMatchesConstructors checks that Sponges with the parameters of
+// the functions of this package produce the same output as they do.
+func TestSpongeMatchesConstructors(t *testing.T) {
+	msg := sequentialBytes(1000)
+	for _, tc := range []struct {
+		name   string
+		rate   int
+		dsbyte byte
+		rounds int
+		newH   func() ShakeHash
+	}{
+		{"SHAKE128", rate128, dsbyteShake, 24, NewShake128},
+		{"SHAKE256", rate256, dsbyteShake, 24, NewShake256},
+		{"SHA3-384", 104, 0x06, 24, func() ShakeHash { return New384().(*state) }},
+		{"TurboSHAKE128", rate128, 0x0b, 12, func() ShakeHash { return NewTurboShake128(0x0b) }},
+	} {
+		sp, err := NewSponge(tc.rate, tc.dsbyte, tc.rounds)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		sp.Absorb(msg[:17])
+		sp.Absorb(msg[17:])
+		sp.Pad()
+		got := make([]byte, 500)
+		sp.Squeeze(got[:3])
+		sp.Squeeze(got[3:])
+
+		h := tc.newH()
+		h.Write(msg)
+		want := make([]byte, len(got))
+		h.Read(want)
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: got %x, want %x", tc.name, got, want)
+		}
+	}
+}
+
+// TestSpongeUnalignedRate checks that a rate that is not a multiple of
+// eight bytes gives the same output however input and output are split.
+func TestSpongeUnalignedRate(t *testing.T) {
+	msg := sequentialBytes(333)
+	newSponge := func() *Sponge {
+		sp, err := NewSponge(101, 0x1f, 24)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sp
+	}
+
+	sp := newSponge()
+	sp.Absorb(msg)
+	want := make([]byte, 407)
+	sp.Squeeze(want)
+
+	sp = newSponge()
+	for i := range msg {
+		sp.Absorb(msg[i : i+1])
+	}
+	got := make([]byte, len(want))
+	for i := range got {
+		sp.Squeeze(got[i : i+1])
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("byte-by-byte output differs:\ngot  %x\nwant %x", got, want)
+	}
+
+	// The bytes past the rate must not leak into the output.
+	sp = newSponge()
+	sp.Absorb(msg)
+	clone := sp.Clone()
+	clone.Pad()
+	out := make([]byte, 101)
+	clone.Squeeze(out)
+	if !bytes.Equal(out, want[:101]) {
+		t.Errorf("clone output differs:\ngot  %x\nwant %x", out, want[:101])
+	}
+}
+
+// TestSpongeParameters checks the validation of the Sponge parameters.
+func TestSpongeParameters(t *testing.T) {
+	for _, tc := range []struct {
+		rate   int
+		dsbyte byte
+		rounds int
+		ok     bool
+	}{
+		{1, 0x01, 1, true},
+		{168, 0x7f, 24, true},
+		{0, 0x1f, 24, false},
+		{169, 0x1f, 24, false},
+		{136, 0x00, 24, false},
+		{136, 0x80, 24, false},
+		{136, 0x1f, 0, false},
+		{136, 0x1f, 25, false},
+	} {
+		_, err := NewSponge(tc.rate, tc.dsbyte, tc.rounds)
+		if (err == nil) != tc.ok {
+			t.Errorf("NewSponge(%d, %#02x, %d): got error %v", tc.rate, tc.dsbyte, tc.rounds, err)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+// This file provides SpongeWrap, the authenticated encryption mode built
+// on a duplex object in "Duplexing the sponge: single-pass authenticated
+// encryption and other applications" by Bertoni, Daemen, Peeters and Van
+// Assche. A single permutation both encrypts and authenticates, in one
+// pass over the data.
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+)
+
+const (
+	// spongeWrapRate is the rate of the duplex object underlying
+	// SpongeWrap, leaving a capacity of 512 bits.
+	spongeWrapRate = rate256
+
+	// spongeWrapBlockSize is the size of the key, header and body blocks,
+	// the maximum input of the duplex object. The frame bit of each block
+	// goes into the byte holding the padding.
+	spongeWrapBlockSize = spongeWrapRate - 1
+
+	// SpongeWrapKeySize is the minimum key size of SpongeWrap.
+	SpongeWrapKeySize = 32
+
+	// SpongeWrapNonceSize is the nonce size of SpongeWrap.
+	SpongeWrapNonceSize = 24
+
+	// SpongeWrapTagSize is the size of the authentication tag appended to
+	// each SpongeWrap ciphertext.
+	SpongeWrapTagSize = 32
+
+	// Padding bytes of the duplex calls, holding the frame bit followed by
+	// the first one bit of the padding.
+	spongeWrapFrame0 = 0x02
+	spongeWrapFrame1 = 0x03
+)
+
+var (
+	errSpongeWrapKey  = errors.New("sha3: SpongeWrap key too short")
+	errSpongeWrapOpen = errors.New("sha3: message authentication failed")
+)
+
+// spongeWrap is a SpongeWrap instance. keyed is the duplex object after
+// the key has been absorbed, which is cloned for each message.
+type spongeWrap struct {
+	keyed *Duplex
+}
+
+// NewSpongeWrap returns a SpongeWrap AEAD keyed with key, which must be at
+// least SpongeWrapKeySize bytes long. The duplex object uses Keccak-f[1600]
+// with a rate of 136 bytes; the header of each message is its nonce
+// followed by the additional data. A nonce must never be used twice with
+// the same key; random nonces of SpongeWrapNonceSize bytes may be used.
+// The AEAD implements Wiper, to clear the keyed state once it is no longer
+// needed.
+func NewSpongeWrap(key []byte) (cipher.AEAD, error) {
+	if len(key) < SpongeWrapKeySize {
+		return nil, errSpongeWrapKey
+	}
+	d := &Duplex{s: &state{rate: spongeWrapRate, dsbyte: 0x01}}
+	// All key blocks but the last have frame bit 1.
+	for len(key) > spongeWrapBlockSize {
+		d.duplex(nil, key[:spongeWrapBlockSize], spongeWrapFrame1)
+		key = key[spongeWrapBlockSize:]
+	}
+	d.duplex(nil, key, spongeWrapFrame0)
+	return &spongeWrap{keyed: d}, nil
+}
+
+func (w *spongeWrap) NonceSize() int { return SpongeWrapNonceSize }
+
+func (w *spongeWrap) Overhead() int { return SpongeWrapTagSize }
+
+// sliceForAppend takes a slice and a requested number of bytes. It returns
+// a slice with the contents of the given slice followed by that many
+// bytes, and a second slice that aliases into it and contains only the
+// extra bytes.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
+
+// wrap runs SpongeWrap on a fresh copy of the keyed duplex object. It
+// absorbs header and encrypts in into out, or decrypts it if decrypt is
+// set, and then writes the tag into tag. out and in may alias exactly.
+func (w *spongeWrap) wrap(out, tag, header, in []byte, decrypt bool) {
+	d := w.keyed.Clone()
+	defer d.Wipe()
+
+	// All header blocks but the last have frame bit 0.
+	for len(header) > spongeWrapBlockSize {
+		d.duplex(nil, header[:spongeWrapBlockSize], spongeWrapFrame0)
+		header = header[spongeWrapBlockSize:]
+	}
+	var z, block [spongeWrapBlockSize]byte
+	n := len(in)
+	if n > spongeWrapBlockSize {
+		n = spongeWrapBlockSize
+	}
+	d.duplex(z[:n], header, spongeWrapFrame1)
+
+	// Each body block is encrypted with the output of the previous call,
+	// and then absorbed: all but the last with frame bit 1.
+	for {
+		if decrypt {
+			for i := 0; i < n; i++ {
+				block[i] = in[i] ^ z[i]
+			}
+			copy(out, block[:n])
+		} else {
+			copy(block[:], in[:n])
+			for i := 0; i < n; i++ {
+				out[i] = block[i] ^ z[i]
+			}
+		}
+		in, out = in[n:], out[n:]
+		if len(in) == 0 {
+			break
+		}
+		last := n
+		n = len(in)
+		if n > spongeWrapBlockSize {
+			n = spongeWrapBlockSize
+		}
+		d.duplex(z[:n], block[:last], spongeWrapFrame1)
+	}
+	d.duplex(tag, block[:n], spongeWrapFrame0)
+
+	// Clear the last key stream and plaintext blocks.
+	for i := range z {
+		z[i], block[i] = 0, 0
+	}
+}
+
+// header returns the SpongeWrap header of a message: the nonce followed
+// by the additional data.
+func (w *spongeWrap) header(nonce, additionalData []byte) []byte {
+	if len(nonce) != SpongeWrapNonceSize {
+		panic("sha3: incorrect nonce length given to SpongeWrap")
+	}
+	return append(append(make([]byte, 0, len(nonce)+len(additionalData)), nonce...), additionalData...)
+}
+
+func (w *spongeWrap) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	header := w.header(nonce, additionalData)
+	ret, out := sliceForAppend(dst, len(plaintext)+SpongeWrapTagSize)
+	w.wrap(out[:len(plaintext)], out[len(plaintext):], header, plaintext, false)
+	return ret
+}
+
+func (w *spongeWrap) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	header := w.header(nonce, additionalData)
+	if len(ciphertext) < SpongeWrapTagSize {
+		return nil, errSpongeWrapOpen
+	}
+	tag := ciphertext[len(ciphertext)-SpongeWrapTagSize:]
+	ciphertext = ciphertext[:len(ciphertext)-SpongeWrapTagSize]
+
+	var expectedTag [SpongeWrapTagSize]byte
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	w.wrap(out, expectedTag[:], header, ciphertext, true)
+	if subtle.ConstantTimeCompare(expectedTag[:], tag) != 1 {
+		for i := range out {
+			out[i] = 0
+		}
+		return nil, errSpongeWrapOpen
+	}
+	return ret, nil
+}
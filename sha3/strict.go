@@ -0,0 +1,90 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+// This file provides a wrapper around ShakeHash values that reports
+// writes after reads as errors. The ShakeHash values of this package
+// panic on such writes, which are programming errors; the wrapper is
+// meant for programs, such as long-running servers reusing instances from
+// a pool, that prefer to handle them.
+
+import (
+	"errors"
+)
+
+// ErrWriteAfterRead is returned by the Write method of a ShakeHash
+// returned by NewStrict when output has already been read from it.
+var ErrWriteAfterRead = errors.New("sha3: write to sponge after read")
+
+// strictShakeHash is a ShakeHash that returns ErrWriteAfterRead rather
+// than panicking.
+type strictShakeHash struct {
+	h       ShakeHash
+	reading bool // whether output has been read since the last Reset
+}
+
+// NewStrict returns a ShakeHash that behaves like h, except that its
+// Write method returns ErrWriteAfterRead, and absorbs nothing, once
+// output has been read from it, until it is reset. If h is a ShakeHash of
+// this package, output may already have been read from it; otherwise it
+// must not have been. h must not be used directly afterwards.
+func NewStrict(h ShakeHash) ShakeHash {
+	return &strictShakeHash{h: h, reading: squeezing(h)}
+}
+
+// squeezing reports whether output has already been read from h, for the
+// ShakeHash values of this package. It returns false for any other
+// ShakeHash, whose state can not be inspected.
+func squeezing(h ShakeHash) bool {
+	switch h := h.(type) {
+	case *state:
+		return h.state == spongeSqueezing
+	case *cshakeState:
+		return h.state.state == spongeSqueezing
+	case *kmacXOF:
+		return h.c.state.state == spongeSqueezing
+	case *kangarooTwelve:
+		return h.final.state == spongeSqueezing
+	case *strictShakeHash:
+		return h.reading
+	}
+	return false
+}
+
+// Write absorbs more data into the hash's state. It returns
+// ErrWriteAfterRead if output has already been read.
+func (s *strictShakeHash) Write(p []byte) (n int, err error) {
+	if s.reading {
+		return 0, ErrWriteAfterRead
+	}
+	return s.h.Write(p)
+}
+
+// Read squeezes an arbitrary number of bytes from the hash.
+func (s *strictShakeHash) Read(out []byte) (n int, err error) {
+	s.reading = true
+	return s.h.Read(out)
+}
+
+// Clone returns a copy of the ShakeHash in its current state.
+func (s *strictShakeHash) Clone() ShakeHash {
+	return &strictShakeHash{h: s.h.Clone(), reading: s.reading}
+}
+
+// Reset resets the ShakeHash to its initial state, in which it accepts
+// writes again.
+func (s *strictShakeHash) Reset() {
+	s.h.Reset()
+	s.reading = false
+}
+
+// Wipe overwrites the state of the wrapped ShakeHash, including any key,
+// with zeros if it implements Wiper. The ShakeHash must not be used
+// afterwards.
+func (s *strictShakeHash) Wipe() {
+	if w, ok := s.h.(Wiper); ok {
+		w.Wipe()
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestStrictWriteAfterRead checks that a strict ShakeHash reports writes
+// after reads as errors, without affecting its output, and accepts writes
+// again after Reset.
+func TestStrictWriteAfterRead(t *testing.T) {
+	for name, newH := range map[string]func() ShakeHash{
+		"SHAKE128":   NewShake128,
+		"KMACXOF256": func() ShakeHash { return NewKMACXOF256([]byte("key"), nil) },
+		"KT128":      func() ShakeHash { return NewKT128(nil) },
+	} {
+		want := make([]byte, 64)
+		ref := newH()
+		ref.Write([]byte(testString))
+		ref.Read(want)
+
+		h := NewStrict(newH())
+		if _, err := h.Write([]byte(testString)); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		got := make([]byte, 64)
+		h.Read(got[:10])
+		for i := 0; i < 2; i++ {
+			if n, err := h.Write([]byte("more")); n != 0 || err != ErrWriteAfterRead {
+				t.Errorf("%s: Write after Read returned %d, %v", name, n, err)
+			}
+		}
+		clone := h.Clone()
+		if _, err := clone.Write([]byte("more")); err != ErrWriteAfterRead {
+			t.Errorf("%s: Write to clone returned %v", name, err)
+		}
+		h.Read(got[10:])
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: got %x, want %x", name, got, want)
+		}
+
+		h.Reset()
+		if _, err := h.Write([]byte(testString)); err != nil {
+			t.Errorf("%s: Write after Reset returned %v", name, err)
+		}
+		h.Read(got)
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: after Reset: got %x, want %x", name, got, want)
+		}
+	}
+}
+
+// TestStrictAlreadyRead checks that wrapping a ShakeHash which has
+// already been read from reports writes as errors rather than panicking.
+func TestStrictAlreadyRead(t *testing.T) {
+	for name, newH := range map[string]func() ShakeHash{
+		"SHAKE128":   NewShake128,
+		"cSHAKE256":  func() ShakeHash { return NewCShake256(nil, []byte("strict")) },
+		"KMACXOF256": func() ShakeHash { return NewKMACXOF256([]byte("key"), nil) },
+		"KT128":      func() ShakeHash { return NewKT128(nil) },
+	} {
+		h := newH()
+		h.Write([]byte(testString))
+		h.Read(make([]byte, 10))
+		if n, err := NewStrict(h).Write([]byte("more")); n != 0 || err != ErrWriteAfterRead {
+			t.Errorf("%s: Write after Read returned %d, %v", name, n, err)
+		}
+		if _, err := NewStrict(NewStrict(h)).Write([]byte("more")); err != ErrWriteAfterRead {
+			t.Errorf("%s: Write to a wrapped strict ShakeHash returned %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+// This file provides TupleHash, the hash function for tuples of byte
+// strings defined in NIST SP 800-185, in both its fixed-output-length
+// and XOF variants.
+
+import (
+	"errors"
+)
+
+// errElementOverflow is returned by TupleHash.Write when more bytes are
+// written than were announced by StartElement.
+var errElementOverflow = errors.New("sha3: write past end of TupleHash element")
+
+// A TupleHash hashes a sequence of byte strings so that the sequence is
+// unambiguous: unlike the concatenation of the strings, ("ab", "c") and
+// ("a", "bc") hash to unrelated values. Elements are added whole with
+// WriteElement, or streamed by calling StartElement with the element's
+// length followed by any number of calls to Write.
+type TupleHash struct {
+	c *cshakeState
+
+	// outputLen is the output length in bytes, which is absorbed before
+	// padding. It is zero for the XOF variants.
+	outputLen int
+
+	// remaining is the number of bytes still expected for the element
+	// announced by the last call to StartElement.
+	remaining int64
+}
+
+func newTupleHash(S []byte, rate, outputLen int) *TupleHash {
+	return &TupleHash{
+		c:         newCShake([]byte("TupleHash"), S, rate),
+		outputLen: outputLen,
+	}
+}
+
+// NewTupleHash128 creates a new TupleHash128 instance producing outputLen
+// bytes of output. customization is an optional customization string.
+func NewTupleHash128(outputLen int, customization []byte) *TupleHash {
+	if outputLen <= 0 {
+		panic("sha3: non-positive TupleHash output length")
+	}
+	return newTupleHash(customization, rate128, outputLen)
+}
+
+// NewTupleHash256 creates a new TupleHash256 instance producing outputLen
+// bytes of output. customization is an optional customization string.
+func NewTupleHash256(outputLen int, customization []byte) *TupleHash {
+	if outputLen <= 0 {
+		panic("sha3: non-positive TupleHash output length")
+	}
+	return newTupleHash(customization, rate256, outputLen)
+}
+
+// NewTupleHashXOF128 creates a new TupleHashXOF128 instance, whose output
+// is read with Read. customization is an optional customization string.
+func NewTupleHashXOF128(customization []byte) *TupleHash {
+	return newTupleHash(customization, rate128, 0)
+}
+
+// NewTupleHashXOF256 creates a new TupleHashXOF256 instance, whose output
+// is read with Read. customization is an optional customization string.
+func NewTupleHashXOF256(customization []byte) *TupleHash {
+	return newTupleHash(customization, rate256, 0)
+}
+
+// checkComplete panics if the last streamed element is incomplete.
+func (t *TupleHash) checkComplete() {
+	if t.remaining != 0 {
+		panic("sha3: TupleHash element is incomplete")
+	}
+}
+
+// WriteElement appends p to the tuple as a single element.
+func (t *TupleHash) WriteElement(p []byte) {
+	t.StartElement(int64(len(p)))
+	t.Write(p)
+}
+
+// StartElement appends a new element of size bytes to the tuple. The
+// content of the element must then be written with Write before the next
+// element is started or any output is produced. It panics if size is
+// negative, or so large that its length in bits does not fit in 64 bits.
+func (t *TupleHash) StartElement(size int64) {
+	t.checkComplete()
+	if size < 0 {
+		panic("sha3: negative TupleHash element size")
+	}
+	if size >= 1<<61 {
+		panic("sha3: TupleHash element size overflows")
+	}
+	t.c.Write(leftEncode(uint64(size) * 8))
+	t.remaining = size
+}
+
+// Write absorbs part of the element announced by StartElement. It returns
+// an error, and absorbs nothing past the end of the element, if p is
+// longer than the rest of the element.
+func (t *TupleHash) Write(p []byte) (n int, err error) {
+	if int64(len(p)) > t.remaining {
+		p = p[:t.remaining]
+		err = errElementOverflow
+	}
+	n, _ = t.c.Write(p)
+	t.remaining -= int64(n)
+	return n, err
+}
+
+// BlockSize returns the rate of the sponge underlying TupleHash.
+func (t *TupleHash) BlockSize() int { return t.c.rate }
+
+// Size returns the output size of TupleHash in bytes, or zero for the XOF
+// variants.
+func (t *TupleHash) Size() int { return t.outputLen }
+
+// Reset resets the TupleHash to an empty tuple.
+func (t *TupleHash) Reset() {
+	t.c.Reset()
+	t.remaining = 0
+}
+
+// Clone returns a copy of the TupleHash in its current state.
+func (t *TupleHash) Clone() *TupleHash {
+	ret := *t
+	ret.c = t.c.Clone().(*cshakeState)
+	return &ret
+}
+
+// Sum appends Size bytes of output for the tuple written so far to in.
+// It does not change the underlying hash state. It panics for the XOF
+// variants, whose output is read with Read.
+func (t *TupleHash) Sum(in []byte) []byte {
+	if t.outputLen == 0 {
+		panic("sha3: Sum called on TupleHashXOF")
+	}
+	t.checkComplete()
+	dup := t.c.clone()
+	dup.Write(rightEncode(uint64(t.outputLen) * 8))
+	hash := make([]byte, t.outputLen)
+	dup.Read(hash)
+	dup.Wipe()
+	return append(in, hash...)
+}
+
+// Read squeezes an arbitrary number of bytes of TupleHashXOF output. No
+// elements can be added to the tuple after output has been read. It panics
+// for the fixed-output-length variants, whose output is produced by Sum.
+func (t *TupleHash) Read(out []byte) (n int, err error) {
+	if t.outputLen != 0 {
+		panic("sha3: Read called on fixed-output-length TupleHash")
+	}
+	if t.c.state.state == spongeAbsorbing {
+		t.checkComplete()
+		t.c.Write(rightEncode(0))
+	}
+	return t.c.Read(out)
+}
+
+// TupleHash128 returns outputLen bytes of the TupleHash128 of tuple with
+// customization string customization.
+func TupleHash128(tuple [][]byte, outputLen int, customization []byte) []byte {
+	t := NewTupleHash128(outputLen, customization)
+	for _, e := range tuple {
+		t.WriteElement(e)
+	}
+	return t.Sum(nil)
+}
+
+// TupleHash256 returns outputLen bytes of the TupleHash256 of tuple with
+// customization string customization.
+func TupleHash256(tuple [][]byte, outputLen int, customization []byte) []byte {
+	t := NewTupleHash256(outputLen, customization)
+	for _, e := range tuple {
+		t.WriteElement(e)
+	}
+	return t.Sum(nil)
+}
@@ -0,0 +1,37 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+// This file provides TurboSHAKE, the extendable-output functions
+// specified in RFC 9861. TurboSHAKE128 and TurboSHAKE256 are SHAKE128 and
+// SHAKE256 with the number of rounds of the permutation halved to 12, and
+// with a domain separation byte chosen by the caller.
+
+// turboRounds is the number of rounds of the permutation used by
+// TurboSHAKE and KangarooTwelve.
+const turboRounds = 12
+
+func newTurboShake(rate int, D byte) *state {
+	if D < 0x01 || D > 0x7f {
+		panic("sha3: TurboSHAKE domain separation byte out of range")
+	}
+	return &state{rate: rate, dsbyte: D, rounds: turboRounds}
+}
+
+// NewTurboShake128 creates a new TurboSHAKE128 variable-output-length
+// ShakeHash with domain separation byte D, which must be in the range
+// 0x01 to 0x7F; 0x1F is the default value in RFC 9861. Instances with
+// different values of D produce unrelated output for the same input. Its
+// generic security strength is 128 bits against all attacks if at least
+// 32 bytes of its output are used.
+func NewTurboShake128(D byte) ShakeHash { return newTurboShake(rate128, D) }
+
+// NewTurboShake256 creates a new TurboSHAKE256 variable-output-length
+// ShakeHash with domain separation byte D, which must be in the range
+// 0x01 to 0x7F; 0x1F is the default value in RFC 9861. Instances with
+// different values of D produce unrelated output for the same input. Its
+// generic security strength is 256 bits against all attacks if at least
+// 64 bytes of its output are used.
+func NewTurboShake256(D byte) ShakeHash { return newTurboShake(rate256, D) }
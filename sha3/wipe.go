@@ -0,0 +1,88 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+// This file provides the Wipe methods, which clear the state of a hash
+// when it is no longer needed, so that a key absorbed into it, as by KMAC
+// or by a MAC that prefixes the message with its key, does not linger in
+// memory. Sum also wipes the copy of the state that it squeezes.
+//
+// Wiping is best effort: the Go runtime may have copied the state
+// elsewhere, for instance when growing a goroutine stack.
+
+// A Wiper is a hash or cipher whose state can be cleared. All the
+// hash.Hash and ShakeHash values returned by this package, and the
+// SpongeWrap AEAD, implement it.
+type Wiper interface {
+	// Wipe overwrites the state of the hash, including any key, with
+	// zeros. The hash must not be used afterwards.
+	Wipe()
+}
+
+// Wipe implements Wiper.
+func (d *state) Wipe() {
+	for i := range d.storage {
+		d.storage[i] = 0
+	}
+	d.Reset()
+}
+
+// Wipe implements Wiper.
+func (f *fixedShake) Wipe() {
+	f.d.Wipe()
+}
+
+// Wipe implements Wiper. It also clears the copy of the key kept for
+// Reset.
+func (k *kmac) Wipe() {
+	k.c.Wipe()
+	for i := range k.keyBlock {
+		k.keyBlock[i] = 0
+	}
+}
+
+// Wipe implements Wiper.
+func (k *kangarooTwelve) Wipe() {
+	k.final.Wipe()
+	k.leaf = k.leaf[:cap(k.leaf)]
+	for i := range k.leaf {
+		k.leaf[i] = 0
+	}
+	k.leaf = k.leaf[:0]
+}
+
+// Wipe overwrites the state of the TupleHash with zeros. The TupleHash
+// must not be used afterwards.
+func (t *TupleHash) Wipe() {
+	t.c.Wipe()
+}
+
+// Wipe overwrites the state of the ParallelHash, including its buffered
+// input, with zeros. The ParallelHash must not be used afterwards.
+func (p *ParallelHash) Wipe() {
+	p.c.Wipe()
+	p.buf = p.buf[:cap(p.buf)]
+	for i := range p.buf {
+		p.buf[i] = 0
+	}
+	p.buf = p.buf[:0]
+}
+
+// Wipe overwrites the state of the sponge with zeros. The Sponge must not
+// be used afterwards.
+func (sp *Sponge) Wipe() {
+	sp.s.Wipe()
+}
+
+// Wipe overwrites the state of the duplex object with zeros. The Duplex
+// must not be used afterwards.
+func (d *Duplex) Wipe() {
+	d.s.Wipe()
+}
+
+// Wipe implements Wiper. It clears the duplex object holding the key.
+func (w *spongeWrap) Wipe() {
+	w.keyed.Wipe()
+}
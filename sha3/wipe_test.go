@@ -0,0 +1,154 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+import (
+	"bytes"
+	"testing"
+)
+
+// isWiped reports whether the sponge state and buffer of d are all zeros.
+func isWiped(d *state) bool {
+	for _, lane := range d.a {
+		if lane != 0 {
+			return false
+		}
+	}
+	for _, b := range d.storage {
+		if b != 0 {
+			return false
+		}
+	}
+	return len(d.buf) == 0
+}
+
+// TestWipe checks that Wipe clears the state of the hashes, both while
+// absorbing and while squeezing.
+func TestWipe(t *testing.T) {
+	key := bytes.Repeat([]byte{0x5c}, 100)
+	for name, newH := range map[string]func() ShakeHash{
+		"SHAKE256": NewShake256,
+		"SHA3-256": func() ShakeHash { return New256().(*state) },
+		"cSHAKE128": func() ShakeHash {
+			return NewCShake128([]byte("N"), []byte("S"))
+		},
+	} {
+		for _, read := range []bool{false, true} {
+			h := newH()
+			h.Write(key)
+			if read {
+				h.Read(make([]byte, 10))
+			}
+			h.(Wiper).Wipe()
+			var d *state
+			switch h := h.(type) {
+			case *state:
+				d = h
+			case *cshakeState:
+				d = h.state
+			}
+			if !isWiped(d) {
+				t.Errorf("%s (read: %v): state not wiped", name, read)
+			}
+		}
+	}
+}
+
+// TestWipeKMAC checks that Wipe clears the key of KMAC, and that it does
+// not affect clones.
+func TestWipeKMAC(t *testing.T) {
+	key := bytes.Repeat([]byte{0x5c}, 32)
+	k := NewKMACXOF256(key, nil).(*kmacXOF)
+	k.Write([]byte("message"))
+	clone := k.Clone()
+	k.Wipe()
+	if !isWiped(k.c.state) {
+		t.Error("KMAC state not wiped")
+	}
+	for _, b := range k.keyBlock {
+		if b != 0 {
+			t.Fatal("KMAC key not wiped")
+		}
+	}
+
+	clone.Reset()
+	clone.Write([]byte("message"))
+	got := make([]byte, 32)
+	clone.Read(got)
+	want := make([]byte, 32)
+	h := NewKMACXOF256(key, nil)
+	h.Write([]byte("message"))
+	h.Read(want)
+	if !bytes.Equal(got, want) {
+		t.Errorf("clone after Wipe: got %x, want %x", got, want)
+	}
+}
+
+// TestWipeBuffers checks that Wipe clears the input buffered by
+// ParallelHash and KangarooTwelve.
+func TestWipeBuffers(t *testing.T) {
+	msg := bytes.Repeat([]byte{0xa5}, 3*k12ChunkSize/2)
+
+	p := NewParallelHash256(1000, 64, nil)
+	p.Write(msg)
+	p.Wipe()
+	if !isWiped(p.c.state) {
+		t.Error("ParallelHash state not wiped")
+	}
+	for _, b := range p.buf[:cap(p.buf)] {
+		if b != 0 {
+			t.Fatal("ParallelHash buffer not wiped")
+		}
+	}
+
+	k := NewKT128(nil).(*kangarooTwelve)
+	k.Write(msg)
+	k.Wipe()
+	if !isWiped(k.final) {
+		t.Error("KangarooTwelve state not wiped")
+	}
+	for _, b := range k.leaf[:cap(k.leaf)] {
+		if b != 0 {
+			t.Fatal("KangarooTwelve buffer not wiped")
+		}
+	}
+}
+
+// TestWipeSpongeWrap checks that Wipe clears the keyed state of the
+// SpongeWrap AEAD.
+func TestWipeSpongeWrap(t *testing.T) {
+	aead, err := NewSpongeWrap(bytes.Repeat([]byte{0x5c}, SpongeWrapKeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead.Seal(nil, make([]byte, SpongeWrapNonceSize), []byte("message"), nil)
+	aead.(Wiper).Wipe()
+	if !isWiped(aead.(*spongeWrap).keyed.s) {
+		t.Error("SpongeWrap key not wiped")
+	}
+}
+
+// plainShakeHash is a ShakeHash from outside the package, which does not
+// implement Wiper.
+type plainShakeHash struct{ ShakeHash }
+
+// TestWiperImplemented checks that the ShakeHash values of the package
+// implement Wiper, and that the strict wrapper also accepts one that does
+// not.
+func TestWiperImplemented(t *testing.T) {
+	for _, h := range []ShakeHash{
+		NewShake128(),
+		NewCShake256(nil, []byte("S")),
+		NewKMACXOF128([]byte("key"), nil),
+		NewKT128(nil),
+		NewTurboShake256(0x1f),
+		NewStrict(NewShake256()),
+	} {
+		if _, ok := h.(Wiper); !ok {
+			t.Errorf("%T does not implement Wiper", h)
+		}
+	}
+	NewStrict(plainShakeHash{NewShake128()}).(Wiper).Wipe()
+}
@@ -0,0 +1,298 @@
+// Package strobe implements the STROBE protocol framework, version 1.0.2,
+// over the Keccak-f[1600] permutation. See https://strobe.sourceforge.io.
+//
+// A Strobe object holds the transcript of a protocol: every operation is
+// absorbed into its state, so that the output of the later ones (keys,
+// tags, ciphertexts) depends on all that came before. Both parties run the
+// same sequence of operations, one with the sending side of each message
+// (SendCLR, SendENC, SendMAC) and the other with the receiving side
+// (RecvCLR, RecvENC, RecvMAC). The first transport operation fixes the
+// role of each party, so that the same operation run by the initiator and
+// by the responder is absorbed differently.
+//
+// The methods named after the operations run them on whole inputs, without
+// the meta flag. Operate runs any operation, with the meta flag for framing
+// data, and can stream an operation over several calls.
+package strobe
+
+import (
+	"crypto/subtle"
+	"errors"
+
+	"code.google.com/p/go.crypto/sha3"
+)
+
+// An Op is a STROBE operation, optionally with the Meta flag set.
+type Op uint8
+
+// Flags of the operations.
+const (
+	flagI Op = 1 << iota // inbound
+	flagA                // application
+	flagC                // cipher
+	flagT                // transport
+	flagM                // meta
+
+	// Meta marks an operation on framing data or metadata rather than on
+	// the data of the protocol, as in OpAD|Meta.
+	Meta = flagM
+)
+
+// The operations.
+const (
+	OpAD      Op = flagA
+	OpKey     Op = flagA | flagC
+	OpPRF     Op = flagI | flagA | flagC
+	OpSendCLR Op = flagA | flagT
+	OpRecvCLR Op = flagI | flagA | flagT
+	OpSendENC Op = flagA | flagC | flagT
+	OpRecvENC Op = flagI | flagA | flagC | flagT
+	OpSendMAC Op = flagC | flagT
+	OpRecvMAC Op = flagI | flagC | flagT
+	OpRatchet Op = flagC
+)
+
+// ErrAuth is returned by RecvMAC, and Operate with OpRecvMAC, when the
+// tag does not match the transcript.
+var ErrAuth = errors.New("strobe: message authentication failed")
+
+var errSecurity = errors.New("strobe: security must be 128 or 256")
+
+// role is the role of a party: the initiator sent the first transport
+// message, and the responder received it.
+type role uint8
+
+const (
+	roleInitiator role = 0
+	roleResponder role = 1
+	roleNone      role = 2
+)
+
+// Strobe is the state of a STROBE protocol.
+type Strobe struct {
+	st       [200]byte
+	r        int // the rate of the duplex, minus the two bytes of padding
+	pos      int
+	posBegin int
+	i0       role
+	curOp    Op
+}
+
+// New returns a new Strobe for the protocol named proto, with a security
+// level of 128 or 256 bits: STROBE-128 or STROBE-256. It returns an error
+// for any other security level.
+func New(proto []byte, security int) (*Strobe, error) {
+	if security != 128 && security != 256 {
+		return nil, errSecurity
+	}
+	s := &Strobe{r: 200 - security/4 - 2, i0: roleNone}
+	copy(s.st[:], []byte{1, byte(s.r + 2), 1, 0, 1, 12 * 8})
+	copy(s.st[6:], "STROBEv1.0.2")
+	sha3.KeccakF1600Bytes(&s.st)
+	s.Operate(OpAD|Meta, proto, false)
+	return s, nil
+}
+
+// Clone returns a copy of s in its current state.
+func (s *Strobe) Clone() *Strobe {
+	dup := *s
+	return &dup
+}
+
+// Wipe overwrites the state of s with zeros. s must not be used afterwards.
+func (s *Strobe) Wipe() {
+	*s = Strobe{}
+}
+
+// runF pads the current block and applies the permutation.
+func (s *Strobe) runF() {
+	s.st[s.pos] ^= byte(s.posBegin)
+	s.st[s.pos+1] ^= 0x04
+	s.st[s.r+1] ^= 0x80
+	sha3.KeccakF1600Bytes(&s.st)
+	s.pos, s.posBegin = 0, 0
+}
+
+// duplex absorbs data, replacing it with the state before absorbing it if
+// cbefore is set, or after if cafter is set. If forceF is set and the
+// current block is not empty, it then permutes the state.
+func (s *Strobe) duplex(data []byte, cbefore, cafter, forceF bool) {
+	for i := range data {
+		if cbefore {
+			data[i] ^= s.st[s.pos]
+		}
+		s.st[s.pos] ^= data[i]
+		if cafter {
+			data[i] = s.st[s.pos]
+		}
+		s.pos++
+		if s.pos == s.r {
+			s.runF()
+		}
+	}
+	if forceF && s.pos != 0 {
+		s.runF()
+	}
+}
+
+// beginOp absorbs the flags of an operation and the start of the previous
+// one.
+func (s *Strobe) beginOp(op Op) {
+	if op&flagT != 0 {
+		if s.i0 == roleNone {
+			s.i0 = role(op & flagI)
+		}
+		op ^= Op(s.i0)
+	}
+	oldBegin := s.posBegin
+	s.posBegin = s.pos + 1
+	s.duplex([]byte{byte(oldBegin), byte(op)}, false, false, op&flagC != 0)
+}
+
+// Operate runs the operation op on buf, in place:
+//
+//	OpAD, OpKey, OpSendCLR, OpRecvCLR: buf is absorbed and left unchanged.
+//	OpSendENC, OpRecvENC: buf is encrypted or decrypted.
+//	OpPRF, OpSendMAC: buf is overwritten with the output.
+//	OpRecvMAC: buf is checked against the expected tag, and overwritten.
+//	OpRatchet: len(buf) bytes of the state are erased, and buf is zeroed.
+//
+// If more is set, the operation continues the previous one, which must
+// have been the same, as if their buffers had been given in a single call.
+// OpRecvMAC can not be continued, since the whole tag must be checked at
+// once.
+//
+// It returns ErrAuth if op is OpRecvMAC and the tag does not match, and
+// nil otherwise. It panics if op is not an operation or if more is set
+// and op is not the previous operation.
+func (s *Strobe) Operate(op Op, buf []byte, more bool) error {
+	switch op &^ Meta {
+	case OpAD, OpKey, OpPRF, OpSendCLR, OpRecvCLR, OpSendENC, OpRecvENC, OpSendMAC, OpRecvMAC, OpRatchet:
+	default:
+		panic("strobe: invalid operation")
+	}
+	if more {
+		if op != s.curOp {
+			panic("strobe: continued operation differs from the previous one")
+		}
+		if op&^Meta == OpRecvMAC {
+			panic("strobe: OpRecvMAC can not be continued")
+		}
+	} else {
+		s.beginOp(op)
+		s.curOp = op
+	}
+
+	// Operations which take a length rather than data run on zeros.
+	if op&(flagI|flagT) != flagI|flagT && op&(flagI|flagA) != flagA {
+		for i := range buf {
+			buf[i] = 0
+		}
+	}
+	cafter := op&(flagC|flagI|flagT) == flagC|flagT
+	cbefore := op&flagC != 0 && !cafter
+	s.duplex(buf, cbefore, cafter, false)
+
+	switch op &^ Meta {
+	case OpRecvMAC:
+		var zero byte
+		for _, b := range buf {
+			zero |= b
+		}
+		if subtle.ConstantTimeByteEq(zero, 0) != 1 {
+			return ErrAuth
+		}
+	case OpRatchet:
+		for i := range buf {
+			buf[i] = 0
+		}
+	}
+	return nil
+}
+
+// AD absorbs associated data.
+func (s *Strobe) AD(data []byte) {
+	s.Operate(OpAD, data, false)
+}
+
+// Key absorbs a secret key, replacing len(key) bytes of the state with
+// it.
+func (s *Strobe) Key(key []byte) {
+	buf := append([]byte(nil), key...)
+	s.Operate(OpKey, buf, false)
+	for i := range buf {
+		buf[i] = 0
+	}
+}
+
+// PRF fills out with pseudorandom bytes derived from the transcript.
+func (s *Strobe) PRF(out []byte) {
+	s.Operate(OpPRF, out, false)
+}
+
+// SendCLR absorbs cleartext data sent to the other party.
+func (s *Strobe) SendCLR(data []byte) {
+	s.Operate(OpSendCLR, data, false)
+}
+
+// RecvCLR absorbs cleartext data received from the other party.
+func (s *Strobe) RecvCLR(data []byte) {
+	s.Operate(OpRecvCLR, data, false)
+}
+
+// SendENC encrypts plaintext, which is sent to the other party, and
+// appends the ciphertext to dst. It does not authenticate the ciphertext;
+// a SendMAC should follow.
+func (s *Strobe) SendENC(dst, plaintext []byte) []byte {
+	ret, out := sliceForAppend(dst, len(plaintext))
+	copy(out, plaintext)
+	s.Operate(OpSendENC, out, false)
+	return ret
+}
+
+// RecvENC decrypts ciphertext, received from the other party, and appends
+// the plaintext to dst. It does not authenticate the ciphertext; a RecvMAC
+// should follow before the plaintext is used.
+func (s *Strobe) RecvENC(dst, ciphertext []byte) []byte {
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	copy(out, ciphertext)
+	s.Operate(OpRecvENC, out, false)
+	return ret
+}
+
+// SendMAC appends an n-byte tag of the transcript, which is sent to the
+// other party, to dst.
+func (s *Strobe) SendMAC(dst []byte, n int) []byte {
+	ret, out := sliceForAppend(dst, n)
+	s.Operate(OpSendMAC, out, false)
+	return ret
+}
+
+// RecvMAC checks the tag received from the other party against the
+// transcript. It returns ErrAuth if they do not match, after which the
+// state no longer matches the sender's.
+func (s *Strobe) RecvMAC(tag []byte) error {
+	return s.Operate(OpRecvMAC, append([]byte(nil), tag...), false)
+}
+
+// Ratchet erases n bytes of the state, so that a compromise of the state
+// does not reveal earlier outputs. The specification suggests n of
+// security/8 bytes.
+func (s *Strobe) Ratchet(n int) {
+	s.Operate(OpRatchet, make([]byte, n), false)
+}
+
+// sliceForAppend takes a slice and a requested number of bytes. It returns
+// a slice with the contents of the given slice followed by that many bytes
+// and a second slice that aliases into it and contains only the extra
+// bytes.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
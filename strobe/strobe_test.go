@@ -0,0 +1,208 @@
+package strobe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+// The test vectors in testdata/test_vectors.json come from StrobeGo
+// (https://github.com/mimoo/StrobeGo) and give the state after each
+// operation.
+type testVector struct {
+	Name       string `json:"name"`
+	Operations []struct {
+		Name         string `json:"name"`
+		CustomString string `json:"custom_string"`
+		Security     int    `json:"security"`
+		Meta         bool   `json:"meta"`
+		InputData    string `json:"input_data"`
+		InputLength  int    `json:"input_length"`
+		Output       string `json:"output"`
+		StateAfter   string `json:"state_after"`
+		Stream       bool   `json:"stream"`
+	} `json:"operations"`
+}
+
+var opNames = map[string]Op{
+	"AD":       OpAD,
+	"KEY":      OpKey,
+	"PRF":      OpPRF,
+	"send_CLR": OpSendCLR,
+	"recv_CLR": OpRecvCLR,
+	"send_ENC": OpSendENC,
+	"recv_ENC": OpRecvENC,
+	"send_MAC": OpSendMAC,
+	"recv_MAC": OpRecvMAC,
+	"RATCHET":  OpRatchet,
+}
+
+func TestVectors(t *testing.T) {
+	data, err := os.ReadFile("testdata/test_vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file struct {
+		TestVectors []testVector `json:"test_vectors"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+	for _, tv := range file.TestVectors {
+		var s *Strobe
+		for i, o := range tv.Operations {
+			if o.Name == "init" {
+				if s, err = New([]byte(o.CustomString), o.Security); err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				op, ok := opNames[o.Name]
+				if !ok {
+					t.Fatalf("%s: unknown operation %q", tv.Name, o.Name)
+				}
+				if o.Meta {
+					op |= Meta
+				}
+				buf := make([]byte, o.InputLength)
+				if o.InputData != "" {
+					buf, _ = hex.DecodeString(o.InputData)
+				}
+				err := s.Operate(op, buf, o.Stream)
+				if op&^Meta == OpRecvMAC {
+					if (err == nil) != (o.Output == "00") {
+						t.Errorf("%s, operation %d: recv_MAC returned %v", tv.Name, i, err)
+					}
+				} else if o.Output != "" && hex.EncodeToString(buf) != o.Output {
+					t.Errorf("%s, operation %d (%s): got %x, want %s", tv.Name, i, o.Name, buf, o.Output)
+				}
+			}
+			if got := hex.EncodeToString(s.st[:]); got != o.StateAfter {
+				t.Fatalf("%s, operation %d (%s): state %s, want %s", tv.Name, i, o.Name, got, o.StateAfter)
+			}
+		}
+	}
+}
+
+// TestMerlin checks the first test vector of Merlin transcripts
+// (https://merlin.cool), which frame messages with streamed meta
+// operations.
+func TestMerlin(t *testing.T) {
+	s, _ := New([]byte("Merlin v1.0"), 128)
+	appendMessage := func(label, msg []byte) {
+		var n [4]byte
+		binary.LittleEndian.PutUint32(n[:], uint32(len(msg)))
+		s.Operate(OpAD|Meta, label, false)
+		s.Operate(OpAD|Meta, n[:], true)
+		s.AD(msg)
+	}
+	appendMessage([]byte("dom-sep"), []byte("test protocol"))
+	appendMessage([]byte("some label"), []byte("some data"))
+	s.Operate(OpAD|Meta, []byte("challenge"), false)
+	s.Operate(OpAD|Meta, []byte{32, 0, 0, 0}, true)
+	out := make([]byte, 32)
+	s.PRF(out)
+	want := "d5a21972d0d5fe320c0d263fac7fffb8145aa640af6e9bca177c03c7efcf0615"
+	if got := hex.EncodeToString(out); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+// TestSendRecv runs both sides of a STROBE-256 exchange. The outputs were
+// computed with an implementation of the pseudocode of the specification.
+func TestSendRecv(t *testing.T) {
+	key := bytes.Repeat([]byte("k"), 32)
+	msg := make([]byte, 200)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	alice, _ := New([]byte("strobe-256 test"), 256)
+	bob, _ := New([]byte("strobe-256 test"), 256)
+	alice.Key(key)
+	bob.Key(key)
+
+	// A message from Alice to Bob, with its length as framing data.
+	alice.Operate(OpAD|Meta, []byte("message 1"), false)
+	bob.Operate(OpAD|Meta, []byte("message 1"), false)
+	ct := alice.SendENC(nil, msg)
+	tag := alice.SendMAC(nil, 16)
+	const wantCT = "4934aa3473afe4a7f6aab646caad6e4f77205171b141de714c6f63f707321634" +
+		"61fb1dcee9ee97e7341fc4c2edf05ccf35521fbf5f1b9298fe0ffe4ed7222b6e" +
+		"0b71d5ded52a6791326b710ac0373869244fc84026af7dd647837c06734b93a1" +
+		"fabc9fb4511b06d25190945a425a8f0e887e329949df42e26a6b577916462cb3" +
+		"e1657db74313fbb19dd02ebacb1c4fd3c1cbaf3681c31518510b558c1f5c91d8" +
+		"a340be31653d6e6bd0716b5bda10306c3b4693b7c3e6de5f81951c68bc82d97e" +
+		"def5f4d517fc6a09"
+	if got := hex.EncodeToString(ct); got != wantCT {
+		t.Errorf("ciphertext: got %s, want %s", got, wantCT)
+	}
+	if got, want := hex.EncodeToString(tag), "9909a2522d689b150f2aedf9975279e2"; got != want {
+		t.Errorf("tag: got %s, want %s", got, want)
+	}
+
+	bad := bob.Clone()
+	if pt := bob.RecvENC(nil, ct); !bytes.Equal(pt, msg) {
+		t.Errorf("decryption: got %x, want %x", pt, msg)
+	}
+	if err := bob.RecvMAC(tag); err != nil {
+		t.Errorf("RecvMAC: %v", err)
+	}
+	ct[0] ^= 1
+	bad.RecvENC(nil, ct)
+	if err := bad.RecvMAC(tag); err != ErrAuth {
+		t.Errorf("RecvMAC of a modified ciphertext returned %v", err)
+	}
+
+	alice.Ratchet(32)
+	bob.Ratchet(32)
+	a, b := make([]byte, 64), make([]byte, 64)
+	alice.PRF(a)
+	bob.PRF(b)
+	want := "7c42fb8e5fbe4abac24080ecef908765a6c8a3e9bd0e80b0e57a6b8316d51e12" +
+		"3828d781e23ef750713811e82071bf9c7a21925ffd9efea8d68cbebc5991c504"
+	if got := hex.EncodeToString(a); got != want {
+		t.Errorf("PRF: got %s, want %s", got, want)
+	}
+	if !bytes.Equal(a, b) {
+		t.Errorf("the parties disagree: %x and %x", a, b)
+	}
+}
+
+// TestStreaming checks that an operation streamed over several calls is
+// the same as a single call.
+func TestStreaming(t *testing.T) {
+	msg := make([]byte, 500)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	for _, op := range []Op{OpAD, OpKey, OpSendCLR, OpRecvENC, OpSendENC | Meta, OpPRF} {
+		s1, _ := New([]byte("streaming"), 128)
+		s2 := s1.Clone()
+		want := append([]byte(nil), msg...)
+		s1.Operate(op, want, false)
+		got := append([]byte(nil), msg...)
+		for i, step := 0, 1; i < len(got); i, step = i+step, step*2+1 {
+			end := i + step
+			if end > len(got) {
+				end = len(got)
+			}
+			s2.Operate(op, got[i:end], i > 0)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("op %#x: streamed output differs", op)
+		}
+		if s1.st != s2.st {
+			t.Errorf("op %#x: streamed state differs", op)
+		}
+	}
+}
+
+func TestNewSecurity(t *testing.T) {
+	for _, security := range []int{0, 64, 192, 512} {
+		if _, err := New(nil, security); err == nil {
+			t.Errorf("New(nil, %d) succeeded", security)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+// Package xofrand derives random-looking values from the output of an
+// extendable-output function such as SHAKE128, so that parameters, test
+// cases or lattice samples can be reproduced from a seed.
+//
+// A Rand reads the output of its ShakeHash in order and maps it to values
+// as documented on each method. The bytes read and the values they give
+// are part of the API: for the same XOF output, every method returns the
+// same values in all releases of this package.
+//
+// All integers are read as little-endian words. Values in a range are
+// drawn by rejection sampling: a word is masked to the number of bits of
+// the largest value in the range and rejected if it is out of range, in
+// which case the next word is read. This is unbiased and reads fewer than
+// two words on average.
+package xofrand
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"math/rand"
+
+	"code.google.com/p/go.crypto/sha3"
+)
+
+// A Rand is a source of values read from a ShakeHash.
+type Rand struct {
+	h   sha3.ShakeHash
+	buf [8]byte
+}
+
+// New returns a Rand reading from h. The input of h should be written
+// before the first call to a method of the Rand.
+func New(h sha3.ShakeHash) *Rand {
+	return &Rand{h: h}
+}
+
+var _ rand.Source64 = (*Rand)(nil)
+
+// Read fills p with the next len(p) bytes of output. It always returns
+// len(p), nil.
+func (r *Rand) Read(p []byte) (int, error) {
+	return r.h.Read(p)
+}
+
+// Uint64 reads 8 bytes and returns them as a little-endian integer.
+func (r *Rand) Uint64() uint64 {
+	r.h.Read(r.buf[:])
+	return binary.LittleEndian.Uint64(r.buf[:])
+}
+
+// Uint32 reads 4 bytes and returns them as a little-endian integer.
+func (r *Rand) Uint32() uint32 {
+	r.h.Read(r.buf[:4])
+	return binary.LittleEndian.Uint32(r.buf[:4])
+}
+
+// Int63 returns a non-negative 63-bit integer: Uint64 shifted right by
+// one bit. With Uint64 and Seed, it makes a Rand a math/rand.Source64, so
+// that rand.New(r) gives the methods of math/rand on the output of the
+// XOF. Only the methods of this package have outputs that do not depend on
+// the release of Go.
+func (r *Rand) Int63() int64 {
+	return int64(r.Uint64() >> 1)
+}
+
+// Seed does nothing: the output of a Rand depends only on the input of
+// its ShakeHash, which is seeded by writing to it before New. Seed is only
+// there to make a Rand a math/rand.Source, so that calling Seed on a
+// math/rand.Rand built on it is harmless.
+func (r *Rand) Seed(seed int64) {}
+
+// Uint64n returns an integer in [0, n). It reads Uint64 words, keeps the
+// low bits.Len64(n-1) bits of each and returns the first that is below n.
+// It panics if n is zero.
+func (r *Rand) Uint64n(n uint64) uint64 {
+	if n == 0 {
+		panic("xofrand: invalid argument to Uint64n")
+	}
+	mask := uint64(1)<<uint(bits.Len64(n-1)) - 1
+	for {
+		if v := r.Uint64() & mask; v < n {
+			return v
+		}
+	}
+}
+
+// Intn returns an integer in [0, n), as Uint64n. It panics if n <= 0.
+func (r *Rand) Intn(n int) int {
+	if n <= 0 {
+		panic("xofrand: invalid argument to Intn")
+	}
+	return int(r.Uint64n(uint64(n)))
+}
+
+// Float64 returns a number in [0.0, 1.0): the top 53 bits of a Uint64
+// word, divided by 2**53.
+func (r *Rand) Float64() float64 {
+	return float64(r.Uint64()>>11) / (1 << 53)
+}
+
+// Shuffle shuffles n elements with the Fisher-Yates algorithm: for i from
+// n-1 down to 1, it calls swap(i, j) with j = Intn(i+1). It panics if
+// n < 0.
+func (r *Rand) Shuffle(n int, swap func(i, j int)) {
+	if n < 0 {
+		panic("xofrand: invalid argument to Shuffle")
+	}
+	for i := n - 1; i > 0; i-- {
+		swap(i, r.Intn(i+1))
+	}
+}
+
+// Perm returns a permutation of [0, n): the integers in increasing order,
+// shuffled by Shuffle. It panics if n < 0.
+func (r *Rand) Perm(n int) []int {
+	if n < 0 {
+		panic("xofrand: invalid argument to Perm")
+	}
+	p := make([]int, n)
+	for i := range p {
+		p[i] = i
+	}
+	r.Shuffle(n, func(i, j int) { p[i], p[j] = p[j], p[i] })
+	return p
+}
+
+// Subset returns k distinct integers of [0, n), in the order they are
+// chosen, with Floyd's algorithm: for j from n-k to n-1, it draws
+// t = Intn(j+1) and chooses t, or j if t was already chosen. It panics if
+// k < 0 or k > n.
+func (r *Rand) Subset(n, k int) []int {
+	if k < 0 || k > n {
+		panic("xofrand: invalid argument to Subset")
+	}
+	s := make([]int, 0, k)
+	chosen := make(map[int]bool, k)
+	for j := n - k; j < n; j++ {
+		t := r.Intn(j + 1)
+		if chosen[t] {
+			t = j
+		}
+		chosen[t] = true
+		s = append(s, t)
+	}
+	return s
+}
+
+// Uniform fills out with integers in [0, q) by rejection sampling, as
+// done for the matrices of lattice schemes. Each candidate is read from
+// the next (bits.Len32(q-1)+7)/8 bytes, as a little-endian integer masked
+// to bits.Len32(q-1) bits, and kept if it is below q. For the modulus
+// 8380417 of ML-DSA, this reads 3 bytes per candidate as in its
+// RejNTTPoly. It panics if q is zero.
+func (r *Rand) Uniform(out []uint32, q uint32) {
+	if q == 0 {
+		panic("xofrand: invalid argument to Uniform")
+	}
+	nbits := bits.Len32(q - 1)
+	n := (nbits + 7) / 8
+	mask := uint32(1)<<uint(nbits) - 1
+	for i := range out {
+		for {
+			r.buf = [8]byte{}
+			r.h.Read(r.buf[:n])
+			if v := binary.LittleEndian.Uint32(r.buf[:4]) & mask; v < q {
+				out[i] = v
+				break
+			}
+		}
+	}
+}
+
+// Wipe wipes the ShakeHash of r, if it implements sha3.Wiper. r must not
+// be used afterwards.
+func (r *Rand) Wipe() {
+	if w, ok := r.h.(sha3.Wiper); ok {
+		w.Wipe()
+	}
+	r.buf = [8]byte{}
+}
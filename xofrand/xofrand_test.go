@@ -0,0 +1,141 @@
+package xofrand
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	"code.google.com/p/go.crypto/sha3"
+)
+
+func newTestRand(seed string) *Rand {
+	h := sha3.NewShake128()
+	h.Write([]byte(seed))
+	return New(h)
+}
+
+// TestStability pins the values read from one XOF output by a sequence of
+// calls. They were computed with an independent implementation of the
+// documented byte consumption.
+func TestStability(t *testing.T) {
+	r := newTestRand("xofrand test")
+	if got, want := r.Uint64(), uint64(0x6a16be9ff8d6e068); got != want {
+		t.Errorf("Uint64: got %#x, want %#x", got, want)
+	}
+	var u []uint64
+	for i := 0; i < 5; i++ {
+		u = append(u, r.Uint64n(1000))
+	}
+	u = append(u, r.Uint64n(1<<40), uint64(r.Intn(3)))
+	if want := []uint64{252, 559, 178, 727, 849, 552634092347, 1}; !reflect.DeepEqual(u, want) {
+		t.Errorf("Uint64n: got %v, want %v", u, want)
+	}
+	if f := []float64{r.Float64(), r.Float64()}; f[0] != 6.524479851077736e-05 || f[1] != 0.8421105980402149 {
+		t.Errorf("Float64: got %v", f)
+	}
+	if got, want := r.Perm(10), []int{7, 1, 5, 0, 2, 8, 9, 3, 6, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Perm: got %v, want %v", got, want)
+	}
+	if got, want := r.Subset(100, 5), []int{46, 82, 69, 39, 10}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Subset: got %v, want %v", got, want)
+	}
+	v := make([]uint32, 8)
+	r.Uniform(v, 8380417)
+	if want := []uint32{4224247, 4108984, 8064873, 7456432, 5044187, 7363084, 41709, 1951130}; !reflect.DeepEqual(v, want) {
+		t.Errorf("Uniform(8380417): got %v, want %v", v, want)
+	}
+	v = v[:4]
+	r.Uniform(v, 3329)
+	if want := []uint32{1589, 413, 2099, 474}; !reflect.DeepEqual(v, want) {
+		t.Errorf("Uniform(3329): got %v, want %v", v, want)
+	}
+	if got, want := r.Uint32(), uint32(0xa6b93cbc); got != want {
+		t.Errorf("Uint32: got %#x, want %#x", got, want)
+	}
+	if got, want := r.Uint64(), uint64(15167144514766231277); got != want {
+		t.Errorf("final Uint64: got %d, want %d", got, want)
+	}
+}
+
+// TestUint64nDistribution checks roughly that Uint64n is uniform for a
+// bound just above a power of two, where rejection is most frequent.
+func TestUint64nDistribution(t *testing.T) {
+	r := newTestRand("distribution")
+	const n, draws = 17, 17 * 2000
+	var counts [n]int
+	for i := 0; i < draws; i++ {
+		counts[r.Uint64n(n)]++
+	}
+	for v, c := range counts {
+		if c < 1700 || c > 2300 {
+			t.Errorf("value %d drawn %d times out of %d", v, c, draws)
+		}
+	}
+}
+
+func TestPermAndSubset(t *testing.T) {
+	r := newTestRand("perm")
+	for _, n := range []int{0, 1, 2, 50} {
+		p := r.Perm(n)
+		sort.Ints(p)
+		for i := range p {
+			if p[i] != i {
+				t.Fatalf("Perm(%d) is not a permutation: %v", n, p)
+			}
+		}
+		for k := 0; k <= n; k += 1 + n/4 {
+			s := r.Subset(n, k)
+			if len(s) != k {
+				t.Fatalf("Subset(%d, %d) has %d elements", n, k, len(s))
+			}
+			sort.Ints(s)
+			for i := range s {
+				if s[i] < 0 || s[i] >= n || i > 0 && s[i] == s[i-1] {
+					t.Fatalf("Subset(%d, %d) = %v", n, k, s)
+				}
+			}
+		}
+	}
+}
+
+func TestSource(t *testing.T) {
+	a := rand.New(newTestRand("source"))
+	b := newTestRand("source")
+	for i := 0; i < 10; i++ {
+		if got, want := a.Int63(), int64(b.Uint64()>>1); got != want {
+			t.Fatalf("Int63: got %d, want %d", got, want)
+		}
+	}
+	if got, want := a.Uint64(), b.Uint64(); got != want {
+		t.Errorf("Uint64: got %d, want %d", got, want)
+	}
+
+	// Seed does not change the output.
+	a.Seed(1)
+	if got, want := a.Int63(), int64(b.Uint64()>>1); got != want {
+		t.Errorf("Int63 after Seed: got %d, want %d", got, want)
+	}
+}
+
+func TestPanics(t *testing.T) {
+	r := newTestRand("panics")
+	for name, f := range map[string]func(){
+		"Uint64n(0)":      func() { r.Uint64n(0) },
+		"Intn(0)":         func() { r.Intn(0) },
+		"Intn(-1)":        func() { r.Intn(-1) },
+		"Perm(-1)":        func() { r.Perm(-1) },
+		"Subset(3, 4)":    func() { r.Subset(3, 4) },
+		"Subset(3, -1)":   func() { r.Subset(3, -1) },
+		"Uniform(out, 0)": func() { r.Uniform(make([]uint32, 1), 0) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s did not panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+}